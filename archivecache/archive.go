@@ -0,0 +1,292 @@
+package archivecache
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	manifestName          = "manifest.json"
+	manifestFormatVersion = 1
+
+	// archiveExtension names the format of the archives, so an archive of another format would be kept under another name.
+	archiveExtension = ".tar.zst"
+)
+
+// zstdMagicNumber starts every zstd frame.
+var zstdMagicNumber = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// manifest lists the archived paths. The content of the n-th path is archived under the `n/` prefix,
+// so the archive does not depend on where the paths are extracted.
+type manifest struct {
+	FormatVersion int             `json:"format_version"`
+	Entries       []manifestEntry `json:"entries"`
+}
+
+type manifestEntry struct {
+	// Root names the dir the path is relative to, empty if the archive has a single dir.
+	Root string `json:"root,omitempty"`
+	Path string `json:"path"`
+	// IndicatorHash is the hash of the indicator file of the path when it was archived, empty if the path has no indicator.
+	IndicatorHash string `json:"indicator_hash,omitempty"`
+}
+
+// pathResolver returns the path the manifest entry is archived from and extracted to.
+type pathResolver func(entry manifestEntry) (string, error)
+
+// relativePath returns a resolver of the entry paths relative to the dir, which must be inside of the dir.
+func relativePath(dir string) pathResolver {
+	return func(entry manifestEntry) (string, error) {
+		if entry.Root != "" {
+			return "", fmt.Errorf("invalid archive root: %s", entry.Root)
+		}
+		return resolveRelativePath(dir, entry.Path)
+	}
+}
+
+// rootRelativePath returns a resolver of the entry paths relative to the dir of their root, which must be inside of that dir.
+func rootRelativePath(roots map[string]string) pathResolver {
+	return func(entry manifestEntry) (string, error) {
+		dir, ok := roots[entry.Root]
+		if !ok || entry.Root == "" {
+			return "", fmt.Errorf("invalid archive root: %s", entry.Root)
+		}
+		return resolveRelativePath(dir, entry.Path)
+	}
+}
+
+func resolveRelativePath(dir, entryPath string) (string, error) {
+	if filepath.IsAbs(entryPath) {
+		return "", fmt.Errorf("invalid archived path: %s", entryPath)
+	}
+	pth, err := safeJoin(dir, entryPath)
+	if err != nil {
+		return "", err
+	}
+	if pth == filepath.Clean(dir) {
+		return "", fmt.Errorf("invalid archived path: %s", entryPath)
+	}
+	return pth, nil
+}
+
+// writeArchive writes the manifest and the content of its entries as a zstd compressed tar archive.
+func writeArchive(w io.Writer, m manifest, resolve pathResolver) error {
+	zstdWriter, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(zstdWriter)
+
+	content, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := tarWriter.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	if _, err := tarWriter.Write(content); err != nil {
+		return err
+	}
+
+	for i, entry := range m.Entries {
+		pth, err := resolve(entry)
+		if err != nil {
+			return err
+		}
+		if err := addPath(tarWriter, strconv.Itoa(i), pth); err != nil {
+			return fmt.Errorf("failed to archive %s, error: %s", entry.Path, err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return zstdWriter.Close()
+}
+
+func addPath(tarWriter *tar.Writer, prefix, root string) error {
+	return filepath.Walk(root, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, pth)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(pth); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(pth)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+}
+
+// extractArchive extracts the content of the archive entries to their resolved paths and returns the manifest of the archive.
+// Existing content of the paths is merged with the archived one, unless replace is set.
+func extractArchive(r io.Reader, resolve pathResolver, replace bool) (manifest, error) {
+	bufferedReader := bufio.NewReader(r)
+	if magicNumber, err := bufferedReader.Peek(len(zstdMagicNumber)); err != nil || !bytes.Equal(magicNumber, zstdMagicNumber) {
+		return manifest{}, errors.New("not a zstd compressed tar archive")
+	}
+	zstdReader, err := zstd.NewReader(bufferedReader)
+	if err != nil {
+		return manifest{}, err
+	}
+	defer zstdReader.Close()
+	tarReader := tar.NewReader(zstdReader)
+
+	header, err := tarReader.Next()
+	if err != nil {
+		return manifest{}, err
+	}
+	if header.Name != manifestName {
+		return manifest{}, fmt.Errorf("%s not found", manifestName)
+	}
+	var m manifest
+	if err := json.NewDecoder(tarReader).Decode(&m); err != nil {
+		return manifest{}, fmt.Errorf("invalid %s: %s", manifestName, err)
+	}
+	if m.FormatVersion != manifestFormatVersion {
+		return manifest{}, fmt.Errorf("unsupported archive format version: %d", m.FormatVersion)
+	}
+	roots := make([]string, len(m.Entries))
+	for i, entry := range m.Entries {
+		root, err := resolve(entry)
+		if err != nil {
+			return manifest{}, err
+		}
+		if replace {
+			if err := os.RemoveAll(root); err != nil {
+				return manifest{}, err
+			}
+		}
+		roots[i] = root
+	}
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return manifest{}, err
+		}
+
+		if err := extractEntry(tarReader, header, roots); err != nil {
+			return manifest{}, fmt.Errorf("failed to extract %s, error: %s", header.Name, err)
+		}
+	}
+
+	return m, nil
+}
+
+func extractEntry(tarReader *tar.Reader, header *tar.Header, roots []string) error {
+	parts := strings.SplitN(strings.TrimSuffix(header.Name, "/"), "/", 2)
+	index, err := strconv.Atoi(parts[0])
+	if err != nil || index < 0 || index >= len(roots) {
+		return errors.New("entry is not listed in the manifest")
+	}
+	root := roots[index]
+
+	target := root
+	if len(parts) == 2 {
+		if target, err = safeJoin(root, parts[1]); err != nil {
+			return err
+		}
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, header.FileInfo().Mode().Perm()|0700)
+	case tar.TypeReg:
+		if err := prepareTarget(target); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, tarReader); err != nil {
+			_ = file.Close()
+			return err
+		}
+		return file.Close()
+	case tar.TypeSymlink:
+		if filepath.IsAbs(header.Linkname) {
+			return fmt.Errorf("absolute link target: %s", header.Linkname)
+		}
+		if linked := filepath.Join(filepath.Dir(target), header.Linkname); !isWithin(root, linked) {
+			return fmt.Errorf("link target is outside of %s: %s", root, header.Linkname)
+		}
+		if err := prepareTarget(target); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, target)
+	default:
+		return nil
+	}
+}
+
+// safeJoin joins the archived relative path to the root, and fails if the result is outside of the root.
+func safeJoin(root, rel string) (string, error) {
+	target := filepath.Join(root, filepath.FromSlash(rel))
+	if !isWithin(root, target) {
+		return "", fmt.Errorf("path is outside of %s: %s", root, rel)
+	}
+	return target, nil
+}
+
+func isWithin(root, pth string) bool {
+	return pth == root || strings.HasPrefix(pth, root+string(filepath.Separator))
+}
+
+// prepareTarget creates the parent dir of the target and removes the existing file or link at the target.
+func prepareTarget(target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package archivecache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenUnsafeEntries_WhenExtractArchiveCalled_ThenExpectError(t *testing.T) {
+	carthageDir := manifestEntry{Root: "project", Path: "Carthage"}
+	testScenarios := []struct {
+		name        string
+		entry       manifestEntry
+		header      tar.Header
+		expectedErr string
+	}{
+		{
+			name:        "absolute archived path",
+			entry:       manifestEntry{Root: "project", Path: "/home/user/.ssh"},
+			header:      tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+			expectedErr: "invalid archived path: /home/user/.ssh",
+		},
+		{
+			name:        "archived path outside of the root",
+			entry:       manifestEntry{Root: "project", Path: "../.ssh"},
+			header:      tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+			expectedErr: "path is outside of /tmp/project: ../.ssh",
+		},
+		{
+			name:        "unknown root",
+			entry:       manifestEntry{Root: "system", Path: "etc"},
+			header:      tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+			expectedErr: "invalid archive root: system",
+		},
+		{
+			name:        "missing root",
+			entry:       manifestEntry{Path: "/tmp/project/Carthage"},
+			header:      tar.Header{Name: "0/", Typeflag: tar.TypeDir, Mode: 0755},
+			expectedErr: "invalid archive root: ",
+		},
+		{
+			name:        "file outside of the archived path",
+			entry:       carthageDir,
+			header:      tar.Header{Name: "0/../../evil", Typeflag: tar.TypeReg, Mode: 0644},
+			expectedErr: "path is outside of /tmp/project/Carthage",
+		},
+		{
+			name:        "link outside of the archived path",
+			entry:       carthageDir,
+			header:      tar.Header{Name: "0/Build", Typeflag: tar.TypeSymlink, Linkname: "../../.ssh"},
+			expectedErr: "link target is outside of /tmp/project/Carthage",
+		},
+		{
+			name:        "absolute link",
+			entry:       carthageDir,
+			header:      tar.Header{Name: "0/Build", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+			expectedErr: "absolute link target: /etc",
+		},
+		{
+			name:        "entry not listed in the manifest",
+			entry:       carthageDir,
+			header:      tar.Header{Name: "1/Build/", Typeflag: tar.TypeDir, Mode: 0755},
+			expectedErr: "entry is not listed in the manifest",
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// Given
+			archive := givenArchive(t, manifest{FormatVersion: manifestFormatVersion, Entries: []manifestEntry{testScenario.entry}}, testScenario.header)
+
+			// When
+			_, err := extractArchive(archive, rootRelativePath(map[string]string{"project": "/tmp/project"}), false)
+
+			// Then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testScenario.expectedErr)
+		})
+	}
+}
+
+func Test_GivenUnknownFormatVersion_WhenExtractArchiveCalled_ThenExpectError(t *testing.T) {
+	// Given
+	archive := givenArchive(t, manifest{FormatVersion: manifestFormatVersion + 1})
+
+	// When
+	_, err := extractArchive(archive, relativePath(t.TempDir()), false)
+
+	// Then
+	assert.EqualError(t, err, "unsupported archive format version: 2")
+}
+
+func Test_GivenGzipCompressedArchive_WhenExtractArchiveCalled_ThenExpectError(t *testing.T) {
+	// Given
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	_, err := gzipWriter.Write([]byte("content"))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	// When
+	_, err = extractArchive(&archive, relativePath(t.TempDir()), false)
+
+	// Then
+	assert.EqualError(t, err, "not a zstd compressed tar archive")
+}
+
+func givenArchive(t *testing.T, m manifest, headers ...tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	tarWriter := tar.NewWriter(zstdWriter)
+
+	content, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = tarWriter.Write(content)
+	require.NoError(t, err)
+
+	for _, header := range headers {
+		header := header
+		require.NoError(t, tarWriter.WriteHeader(&header))
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, zstdWriter.Close())
+	return &buf
+}
@@ -0,0 +1,66 @@
+package archivecache
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ArtifactStore keeps archives of files under a directory, like the build products of a single dependency,
+// so they can be shared between the projects using the same key.
+type ArtifactStore struct {
+	storage Storage
+}
+
+// NewArtifactStore ...
+func NewArtifactStore(storage Storage) ArtifactStore {
+	return ArtifactStore{storage: storage}
+}
+
+// Restore extracts the artifact of the key into the dir, replacing the existing content of its paths.
+// The returned bool is false if the store has no artifact with the key.
+func (store ArtifactStore) Restore(key, dir string) (bool, error) {
+	content, found, err := store.storage.Get(key + archiveExtension)
+	if err != nil || !found {
+		return false, err
+	}
+	defer func() {
+		_ = content.Close()
+	}()
+
+	if _, err := extractArchive(content, relativePath(dir), true); err != nil {
+		return false, fmt.Errorf("failed to extract artifact, error: %s", err)
+	}
+	return true, nil
+}
+
+// Save archives the paths, relative to the dir, as the artifact of the key.
+func (store ArtifactStore) Save(key, dir string, paths []string) error {
+	m := manifest{FormatVersion: manifestFormatVersion}
+	for _, pth := range paths {
+		m.Entries = append(m.Entries, manifestEntry{Path: pth})
+	}
+
+	archive, err := ioutil.TempFile("", "carthage-artifact-*"+archiveExtension)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = archive.Close()
+		_ = os.Remove(archive.Name())
+	}()
+
+	if err := writeArchive(archive, m, relativePath(dir)); err != nil {
+		return fmt.Errorf("failed to create artifact, error: %s", err)
+	}
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return store.storage.Put(key+archiveExtension, archive, size)
+}
@@ -0,0 +1,61 @@
+package archivecache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenSavedArtifact_WhenRestoreCalled_ThenExpectBuildProductsReplaced(t *testing.T) {
+	// Given
+	store := NewArtifactStore(NewLocalStorage(t.TempDir()))
+	buildDir := filepath.Join(givenCarthageDir(t, t.TempDir()), "Build")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(buildDir, ".Alamofire.version"), []byte("{}"), 0644))
+
+	otherBuildDir := filepath.Join(t.TempDir(), "Carthage", "Build")
+	staleFile := filepath.Join(otherBuildDir, "iOS/Alamofire.framework/Stale")
+	require.NoError(t, os.MkdirAll(filepath.Dir(staleFile), 0755))
+	require.NoError(t, ioutil.WriteFile(staleFile, nil, 0644))
+
+	// When
+	err := store.Save("Alamofire-key", buildDir, []string{".Alamofire.version", "iOS/Alamofire.framework"})
+	require.NoError(t, err)
+	restored, restoreErr := store.Restore("Alamofire-key", otherBuildDir)
+
+	// Then
+	require.NoError(t, restoreErr)
+	assert.True(t, restored)
+	assertFileContent(t, filepath.Join(otherBuildDir, ".Alamofire.version"), "{}")
+	assertFileContent(t, filepath.Join(otherBuildDir, "iOS/Alamofire.framework/Alamofire"), "binary")
+	assert.NoFileExists(t, staleFile)
+}
+
+func Test_GivenMissingArtifact_WhenRestoreCalled_ThenExpectNotRestored(t *testing.T) {
+	// Given
+	store := NewArtifactStore(NewLocalStorage(t.TempDir()))
+
+	// When
+	restored, err := store.Restore("Alamofire-key", t.TempDir())
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, restored)
+}
+
+func Test_GivenPathsOutsideOfDir_WhenSaveCalled_ThenExpectError(t *testing.T) {
+	// Given
+	store := NewArtifactStore(NewLocalStorage(t.TempDir()))
+	buildDir := t.TempDir()
+
+	for _, pth := range []string{".", "../Checkouts", "/etc"} {
+		// When
+		err := store.Save("Alamofire-key", buildDir, []string{pth})
+
+		// Then
+		assert.Error(t, err, pth)
+	}
+}
@@ -0,0 +1,215 @@
+package archivecache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// Cache archives the included paths into a Storage and restores them from it,
+// so the step can cache without the Cache:Pull and Cache:Push steps.
+// The paths are archived relative to the dir of their root, so the archive restores them under the roots
+// of the machine restoring it, and only there.
+type Cache struct {
+	storage Storage
+	key     string
+	// roots maps the root names to their dirs, like the project dir and the home dir.
+	roots   map[string]string
+	include []string
+	// restored maps the restored entries to the hash of their indicator, nil if nothing was restored.
+	restored map[string]string
+}
+
+// New creates a Cache, which keeps its archive under the key in the storage, named `<key>.tar.zst`.
+// Every included path needs to be inside of the dir of a root.
+func New(storage Storage, key string, roots map[string]string) *Cache {
+	return &Cache{
+		storage: storage,
+		key:     key,
+		roots:   roots,
+	}
+}
+
+// IncludePath adds the paths to the cache. Like the Cache:Push step, an item may name an indicator file
+// in the `path -> indicator` format, the path is uploaded only if its indicator has changed.
+func (cache *Cache) IncludePath(items ...string) {
+	cache.include = append(cache.include, items...)
+}
+
+// Restore downloads and extracts the archive of the cache key, and returns false if there is no archive yet.
+func (cache *Cache) Restore() (bool, error) {
+	content, found, err := cache.storage.Get(cache.archiveName())
+	if err != nil {
+		return false, fmt.Errorf("failed to download cache archive, error: %s", err)
+	} else if !found {
+		return false, nil
+	}
+	defer func() {
+		_ = content.Close()
+	}()
+
+	m, err := extractArchive(content, rootRelativePath(cache.roots), false)
+	if err != nil {
+		return false, fmt.Errorf("failed to extract cache archive, error: %s", err)
+	}
+
+	cache.restored = map[string]string{}
+	for _, entry := range m.Entries {
+		cache.restored[entryID(entry)] = entry.IndicatorHash
+	}
+	return true, nil
+}
+
+// Commit archives the included paths and uploads the archive, unless every path and its indicator is unchanged since the restore.
+func (cache *Cache) Commit() error {
+	m, err := cache.manifest()
+	if err != nil {
+		return err
+	}
+
+	if cache.isUnchanged(m) {
+		log.Printf("Cached paths are unchanged since restore, skipping cache upload")
+		return nil
+	}
+
+	archive, err := ioutil.TempFile("", "carthage-cache-*"+archiveExtension)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = archive.Close()
+		_ = os.Remove(archive.Name())
+	}()
+
+	if err := writeArchive(archive, m, rootRelativePath(cache.roots)); err != nil {
+		return fmt.Errorf("failed to create cache archive, error: %s", err)
+	}
+	size, err := archive.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := cache.storage.Put(cache.archiveName(), archive, size); err != nil {
+		return fmt.Errorf("failed to upload cache archive, error: %s", err)
+	}
+	log.Printf("Uploaded cache archive (%d bytes)", size)
+	return nil
+}
+
+// Location returns where the archive of the cache key is kept in the storage.
+func (cache *Cache) Location() string {
+	return cache.storage.Location(cache.archiveName())
+}
+
+func (cache *Cache) archiveName() string {
+	return cache.key + archiveExtension
+}
+
+// manifest lists the existing included paths, with the hash of their indicator.
+// Files without an indicator are their own indicators.
+func (cache *Cache) manifest() (manifest, error) {
+	m := manifest{FormatVersion: manifestFormatVersion}
+	seen := map[string]bool{}
+
+	for _, item := range cache.include {
+		pth, indicator := item, ""
+		if split := strings.SplitN(item, "->", 2); len(split) == 2 {
+			pth, indicator = strings.TrimSpace(split[0]), strings.TrimSpace(split[1])
+		}
+
+		if seen[pth] {
+			continue
+		}
+		seen[pth] = true
+
+		info, err := os.Lstat(pth)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return manifest{}, err
+		}
+
+		if indicator == "" && info.Mode().IsRegular() {
+			indicator = pth
+		}
+		indicatorHash, err := hashFile(indicator)
+		if err != nil {
+			return manifest{}, err
+		}
+
+		root, rel, err := cache.rootOf(pth)
+		if err != nil {
+			return manifest{}, err
+		}
+		m.Entries = append(m.Entries, manifestEntry{Root: root, Path: rel, IndicatorHash: indicatorHash})
+	}
+
+	return m, nil
+}
+
+// rootOf returns the root of the path, the one with the closest dir, and the path relative to its dir.
+func (cache *Cache) rootOf(pth string) (string, string, error) {
+	var root, rel string
+	for name, dir := range cache.roots {
+		candidate, err := filepath.Rel(dir, pth)
+		if err != nil || candidate == "." || candidate == ".." || strings.HasPrefix(candidate, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if root == "" || len(candidate) < len(rel) || (len(candidate) == len(rel) && name < root) {
+			root, rel = name, candidate
+		}
+	}
+	if root == "" {
+		return "", "", fmt.Errorf("%s is not inside of the cached dirs", pth)
+	}
+	return root, filepath.ToSlash(rel), nil
+}
+
+func (cache *Cache) isUnchanged(m manifest) bool {
+	if cache.restored == nil || len(m.Entries) != len(cache.restored) {
+		return false
+	}
+	for _, entry := range m.Entries {
+		if restoredHash, ok := cache.restored[entryID(entry)]; !ok || entry.IndicatorHash == "" || entry.IndicatorHash != restoredHash {
+			return false
+		}
+	}
+	return true
+}
+
+func entryID(entry manifestEntry) string {
+	return path.Join(entry.Root, entry.Path)
+}
+
+// hashFile returns the sha256 hash of the file, empty if there is no such file.
+func hashFile(pth string) (string, error) {
+	if pth == "" {
+		return "", nil
+	}
+
+	file, err := os.Open(pth)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
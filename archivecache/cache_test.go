@@ -0,0 +1,192 @@
+package archivecache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenCommittedCache_WhenRestoreCalled_ThenExpectPathsRestoredUnderRoots(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	storage := NewLocalStorage(t.TempDir())
+	carthageDir := givenCarthageDir(t, projectDir)
+	cacheFile := filepath.Join(carthageDir, "Cachefile")
+
+	cache := New(storage, "carthage-key", map[string]string{"project": projectDir})
+	cache.IncludePath(carthageDir + " -> " + cacheFile)
+
+	// When
+	err := cache.Commit()
+	require.NoError(t, err)
+	otherProjectDir := t.TempDir()
+	restored, restoreErr := New(storage, "carthage-key", map[string]string{"project": otherProjectDir}).Restore()
+
+	// Then
+	require.NoError(t, restoreErr)
+	assert.True(t, restored)
+	otherCarthageDir := filepath.Join(otherProjectDir, "Carthage")
+	assertFileContent(t, filepath.Join(otherCarthageDir, "Build/iOS/Alamofire.framework/Alamofire"), "binary")
+	assertFileContent(t, filepath.Join(otherCarthageDir, "Cachefile"), "cachefile")
+	link, err := os.Readlink(filepath.Join(otherCarthageDir, "Build/iOS/Alamofire.framework/Current"))
+	require.NoError(t, err)
+	assert.Equal(t, "Alamofire", link)
+	info, err := os.Stat(filepath.Join(otherCarthageDir, "Build/iOS/Alamofire.framework/Alamofire"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+}
+
+func Test_GivenNoArchive_WhenRestoreCalled_ThenExpectNotRestored(t *testing.T) {
+	// Given
+	cache := New(NewLocalStorage(t.TempDir()), "carthage-key", nil)
+
+	// When
+	restored, err := cache.Restore()
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, restored)
+}
+
+func Test_GivenLocalStorage_WhenLocationCalled_ThenExpectArchivePath(t *testing.T) {
+	// Given
+	dir := t.TempDir()
+	cache := New(NewLocalStorage(dir), "carthage-key", nil)
+
+	// When
+	location := cache.Location()
+
+	// Then
+	assert.Equal(t, filepath.Join(dir, "carthage-key"+archiveExtension), location)
+}
+
+func Test_GivenUnchangedRestoredPaths_WhenCommitCalled_ThenExpectUploadSkipped(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	storageDir := t.TempDir()
+	carthageDir := givenCarthageDir(t, projectDir)
+	include := carthageDir + " -> " + filepath.Join(carthageDir, "Cachefile")
+
+	initial := New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir})
+	initial.IncludePath(include)
+	require.NoError(t, initial.Commit())
+	archivePth := filepath.Join(storageDir, "carthage-key"+archiveExtension)
+	before, err := os.Stat(archivePth)
+	require.NoError(t, err)
+
+	cache := New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir})
+	_, err = cache.Restore()
+	require.NoError(t, err)
+	cache.IncludePath(include)
+
+	// When
+	err = cache.Commit()
+
+	// Then
+	require.NoError(t, err)
+	after, err := os.Stat(archivePth)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+}
+
+func Test_GivenChangedIndicator_WhenCommitCalled_ThenExpectArchiveUploaded(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	storageDir := t.TempDir()
+	carthageDir := givenCarthageDir(t, projectDir)
+	cacheFile := filepath.Join(carthageDir, "Cachefile")
+
+	initial := New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir})
+	initial.IncludePath(carthageDir + " -> " + cacheFile)
+	require.NoError(t, initial.Commit())
+
+	cache := New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir})
+	_, err := cache.Restore()
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(cacheFile, []byte("updated cachefile"), 0644))
+	cache.IncludePath(carthageDir + " -> " + cacheFile)
+
+	// When
+	err = cache.Commit()
+
+	// Then
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(carthageDir))
+	_, err = New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir}).Restore()
+	require.NoError(t, err)
+	assertFileContent(t, cacheFile, "updated cachefile")
+}
+
+func Test_GivenMissingPaths_WhenManifestCalled_ThenExpectMissingPathsSkipped(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	storageDir := t.TempDir()
+	carthageDir := givenCarthageDir(t, projectDir)
+
+	cache := New(NewLocalStorage(storageDir), "carthage-key", map[string]string{"project": projectDir})
+	cache.IncludePath(carthageDir, filepath.Join(projectDir, "missing"))
+
+	// When
+	m, err := cache.manifest()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []manifestEntry{{Root: "project", Path: "Carthage"}}, m.Entries)
+}
+
+func Test_GivenNestedRoots_WhenManifestCalled_ThenExpectPathsRelativeToClosestRoot(t *testing.T) {
+	// Given
+	homeDir := t.TempDir()
+	projectDir := filepath.Join(homeDir, "app")
+	carthageDir := givenCarthageDir(t, projectDir)
+	carthageKitDir := filepath.Join(homeDir, "Library", "Caches", "org.carthage.CarthageKit")
+	require.NoError(t, os.MkdirAll(carthageKitDir, 0755))
+
+	cache := New(NewLocalStorage(t.TempDir()), "carthage-key", map[string]string{"project": projectDir, "home": homeDir})
+	cache.IncludePath(carthageDir, carthageKitDir)
+
+	// When
+	m, err := cache.manifest()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []manifestEntry{
+		{Root: "project", Path: "Carthage"},
+		{Root: "home", Path: "Library/Caches/org.carthage.CarthageKit"},
+	}, m.Entries)
+}
+
+func Test_GivenPathOutsideOfRoots_WhenCommitCalled_ThenExpectError(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	otherDir := t.TempDir()
+	cache := New(NewLocalStorage(t.TempDir()), "carthage-key", map[string]string{"project": projectDir})
+	cache.IncludePath(otherDir)
+
+	// When
+	err := cache.Commit()
+
+	// Then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not inside of the cached dirs")
+}
+
+func givenCarthageDir(t *testing.T, projectDir string) string {
+	carthageDir := filepath.Join(projectDir, "Carthage")
+	frameworkDir := filepath.Join(carthageDir, "Build/iOS/Alamofire.framework")
+	require.NoError(t, os.MkdirAll(frameworkDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(frameworkDir, "Alamofire"), []byte("binary"), 0755))
+	require.NoError(t, os.Symlink("Alamofire", filepath.Join(frameworkDir, "Current")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(carthageDir, "Cachefile"), []byte("cachefile"), 0644))
+	return carthageDir
+}
+
+func assertFileContent(t *testing.T, pth, expected string) {
+	content, err := ioutil.ReadFile(pth)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
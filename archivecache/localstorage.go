@@ -0,0 +1,59 @@
+package archivecache
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage keeps the cache archives in a local (or mounted) directory.
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage ...
+func NewLocalStorage(dir string) LocalStorage {
+	return LocalStorage{dir: dir}
+}
+
+// Get ...
+func (storage LocalStorage) Get(name string) (io.ReadCloser, bool, error) {
+	file, err := os.Open(filepath.Join(storage.dir, name))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return file, true, nil
+}
+
+// Location ...
+func (storage LocalStorage) Location(name string) string {
+	return filepath.Join(storage.dir, name)
+}
+
+// Put writes the archive to a temporary file first, so concurrent builds never read a partially written archive.
+func (storage LocalStorage) Put(name string, content io.ReadSeeker, size int64) error {
+	if err := os.MkdirAll(storage.dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(storage.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := io.Copy(file, content); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filepath.Join(storage.dir, name))
+}
@@ -0,0 +1,50 @@
+package archivecache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenPutArchive_WhenLocalStorageGetCalled_ThenExpectContent(t *testing.T) {
+	// Given
+	dir := filepath.Join(t.TempDir(), "cache")
+	storage := NewLocalStorage(dir)
+
+	// When
+	err := storage.Put("archive.tar.zst", strings.NewReader("content"), 7)
+
+	// Then
+	require.NoError(t, err)
+	content, found, err := storage.Get("archive.tar.zst")
+	require.NoError(t, err)
+	require.True(t, found)
+	defer func() {
+		_ = content.Close()
+	}()
+	b, err := ioutil.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_GivenMissingArchive_WhenLocalStorageGetCalled_ThenExpectNotFound(t *testing.T) {
+	// Given
+	storage := NewLocalStorage(t.TempDir())
+
+	// When
+	content, found, err := storage.Get("archive.tar.zst")
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, content)
+}
@@ -0,0 +1,173 @@
+package archivecache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service          = "s3"
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3SignedHeaders    = "host;x-amz-content-sha256;x-amz-date"
+	// emptyPayloadHash is the payload hash of the GET requests, which have no body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3Storage keeps the cache archives in an S3 compatible object storage, like AWS S3, MinIO or Google Cloud Storage (with HMAC keys).
+// The requests are signed with AWS Signature Version 4, or sent unsigned if no access key is set.
+type S3Storage struct {
+	bucketURL       *url.URL
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3Storage creates an S3Storage from the path-style URL of the bucket, optionally followed by a key prefix,
+// like https://s3.eu-west-1.amazonaws.com/my-bucket/carthage.
+func NewS3Storage(bucketURL, region, accessKeyID, secretAccessKey string) (S3Storage, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return S3Storage{}, fmt.Errorf("invalid bucket URL: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return S3Storage{}, fmt.Errorf("invalid bucket URL: %s, expected a path-style URL like https://s3.<region>.amazonaws.com/<bucket>", bucketURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+
+	return S3Storage{
+		bucketURL:       u,
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		client:          &http.Client{Timeout: 30 * time.Minute},
+		now:             time.Now,
+	}, nil
+}
+
+// Get ...
+func (storage S3Storage) Get(name string) (io.ReadCloser, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, storage.objectURL(name), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	storage.sign(req, emptyPayloadHash)
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, true, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, false, nil
+	default:
+		return nil, false, responseError(resp)
+	}
+}
+
+// Put ...
+func (storage S3Storage) Put(name string, content io.ReadSeeker, size int64) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, storage.objectURL(name), ioutil.NopCloser(content))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/zstd")
+	storage.sign(req, hex.EncodeToString(hash.Sum(nil)))
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Location ...
+func (storage S3Storage) Location(name string) string {
+	return storage.objectURL(name)
+}
+
+func (storage S3Storage) objectURL(name string) string {
+	u := *storage.bucketURL
+	u.Path = u.Path + "/" + name
+	return u.String()
+}
+
+// sign adds the AWS Signature Version 4 authorization header to the request,
+// see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func (storage S3Storage) sign(req *http.Request, payloadHash string) {
+	now := storage.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	if storage.accessKeyID == "" {
+		return
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format("20060102"), storage.region, s3Service, "aws4_request"}, "/")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3SigningAlgorithm, amzDate, scope, hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	key := []byte("AWS4" + storage.secretAccessKey)
+	for _, part := range []string{now.Format("20060102"), storage.region, s3Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, storage.accessKeyID, scope, s3SignedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func responseError(resp *http.Response) error {
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s: %s %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+}
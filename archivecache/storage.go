@@ -0,0 +1,12 @@
+package archivecache
+
+import "io"
+
+// Storage keeps the cache archives by name.
+type Storage interface {
+	// Get returns the content of the archive, and false if there is no archive with the name.
+	Get(name string) (io.ReadCloser, bool, error)
+	Put(name string, content io.ReadSeeker, size int64) error
+	// Location returns where the archive with the name is kept, like its path or URL.
+	Location(name string) string
+}
@@ -0,0 +1,253 @@
+package cachedcarthage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+// ArtifactStore keeps files of a dir by key, shared between projects.
+type ArtifactStore interface {
+	// Restore extracts the files of the key into the dir, the returned bool is false if the store has no such key.
+	Restore(key, dir string) (bool, error)
+	// Save stores the paths (relative to the dir) under the key.
+	Save(key, dir string, paths []string) error
+}
+
+// artifactKey identifies the build products of a dependency: the same pinned version of the same dependency,
+// built against the same pinned versions of its dependencies, with the same toolchain and build settings,
+// has the same build products in every project.
+type artifactKey struct {
+	Dependency string `json:"dependency"`
+	URL        string `json:"url"`
+	Pin        string `json:"pin"`
+	// DependencyPins are the pins of the transitive dependencies of the dependency, by name.
+	DependencyPins map[string]string `json:"dependency_pins"`
+	Fingerprint    Fingerprint       `json:"fingerprint"`
+}
+
+func newArtifactKey(dependency cartfile.ResolvedDependency, dependencyPins map[string]string, fingerprint Fingerprint) artifactKey {
+	return artifactKey{
+		Dependency:     dependency.Name(),
+		URL:            dependency.URL(),
+		Pin:            dependency.Pin,
+		DependencyPins: dependencyPins,
+		Fingerprint:    fingerprint.normalized(),
+	}
+}
+
+// String returns the dependency name followed by the hash of the key.
+func (key artifactKey) String() string {
+	// a struct of strings, booleans and string maps always marshals, with the map keys sorted
+	content, _ := json.Marshal(key)
+	return fmt.Sprintf("%s-%x", key.Dependency, sha256.Sum256(content))
+}
+
+// ArtifactCache restores the build products of single dependencies from an ArtifactStore,
+// and saves the build products Carthage built into it.
+type ArtifactCache struct {
+	project       Project
+	fingerprint   Fingerprint
+	store         ArtifactStore
+	stateProvider ProjectStateProvider
+}
+
+// NewArtifactCache ...
+func NewArtifactCache(project Project, fingerprint Fingerprint, store ArtifactStore, stateProvider ProjectStateProvider) ArtifactCache {
+	return ArtifactCache{
+		project:       project,
+		fingerprint:   fingerprint,
+		store:         store,
+		stateProvider: stateProvider,
+	}
+}
+
+// Restore restores the build products of the given dependencies (every resolved dependency if empty),
+// and returns the restored dependencies and the ones which still need to be built.
+// Dependencies without an artifact key are never restored (see artifactKeys).
+func (cache ArtifactCache) Restore(dependencyNames []string) ([]string, []string, error) {
+	dependencies, keys, buildDir, err := cache.resolvedDependencies(dependencyNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var restored, missing []string
+	for _, dependency := range dependencies {
+		if key, ok := keys.keys[dependency.Name()]; ok && cache.restore(dependency, key, buildDir) {
+			restored = append(restored, dependency.Name())
+		} else {
+			missing = append(missing, dependency.Name())
+		}
+	}
+
+	return restored, missing, nil
+}
+
+func (cache ArtifactCache) restore(dependency cartfile.ResolvedDependency, key artifactKey, buildDir string) bool {
+	found, err := cache.store.Restore(key.String(), buildDir)
+	if err != nil {
+		log.Warnf("Failed to restore %s from the artifact store, error: %s", dependency.Name(), err)
+		return false
+	} else if !found {
+		return false
+	}
+
+	reason, err := checkBuildProducts(buildDir, dependency)
+	if err != nil {
+		log.Warnf("Failed to check the restored build products of %s, error: %s", dependency.Name(), err)
+		return false
+	} else if reason != "" {
+		log.Warnf("Restored build products of %s are invalid: %s", dependency.Name(), reason)
+		return false
+	}
+
+	return true
+}
+
+// Save stores the build products of the given dependencies (every resolved dependency if empty).
+// Failing to store a dependency does not prevent storing the others.
+func (cache ArtifactCache) Save(dependencyNames []string) error {
+	dependencies, keys, buildDir, err := cache.resolvedDependencies(dependencyNames)
+	if err != nil {
+		return err
+	}
+
+	for _, dependency := range dependencies {
+		key, ok := keys.keys[dependency.Name()]
+		if !ok {
+			log.Printf("- %s: skipped, %s", dependency.Name(), keys.skipReasons[dependency.Name()])
+			continue
+		}
+
+		if err := cache.save(dependency, key, buildDir); err != nil {
+			log.Warnf("- %s: %s", dependency.Name(), err)
+			continue
+		}
+		log.Printf("- %s: stored", dependency.Name())
+	}
+
+	return nil
+}
+
+func (cache ArtifactCache) save(dependency cartfile.ResolvedDependency, key artifactKey, buildDir string) error {
+	reason, err := checkBuildProducts(buildDir, dependency)
+	if err != nil {
+		return err
+	} else if reason != "" {
+		return fmt.Errorf("build products are incomplete: %s", reason)
+	}
+
+	file, _, err := readVersionFile(buildDir, dependency.Name())
+	if err != nil {
+		return err
+	}
+
+	return cache.store.Save(key.String(), buildDir, file.artifactPaths(buildDir, dependency.Name()))
+}
+
+// artifactKeys are the artifact keys of the resolved dependencies, by name,
+// and the reasons why the dependencies without one can not be stored and restored.
+type artifactKeys struct {
+	keys        map[string]artifactKey
+	skipReasons map[string]string
+}
+
+// resolvedDependencies returns the resolved dependencies of the given names (every resolved dependency if empty),
+// the artifact keys of the resolved dependencies and the absolute build dir.
+func (cache ArtifactCache) resolvedDependencies(names []string) ([]cartfile.ResolvedDependency, artifactKeys, string, error) {
+	state, err := cache.stateProvider.ParseState(cache.project)
+	if err != nil {
+		return nil, artifactKeys{}, "", err
+	}
+
+	keys, err := cache.artifactKeys(state.resolvedDependencies)
+	if err != nil {
+		return nil, artifactKeys{}, "", err
+	}
+
+	buildDir, err := filepath.Abs(cache.project.buildDir())
+	if err != nil {
+		return nil, artifactKeys{}, "", fmt.Errorf("failed to determine absolute build dir")
+	}
+
+	if len(names) == 0 {
+		return state.resolvedDependencies, keys, buildDir, nil
+	}
+
+	var dependencies []cartfile.ResolvedDependency
+	for _, dependency := range state.resolvedDependencies {
+		if contains(names, dependency.Name()) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies, keys, buildDir, nil
+}
+
+// artifactKeys returns the artifact keys of the resolved dependencies. A dependency has no key if it or one of its
+// transitive dependencies is pinned to a branch, as the branch may have moved since it was stored,
+// or if a checkout is missing, as then the dependencies it is built against are unknown.
+func (cache ArtifactCache) artifactKeys(resolvedDependencies []cartfile.ResolvedDependency) (artifactKeys, error) {
+	graph, err := cache.project.dependencyGraph()
+	if err != nil {
+		return artifactKeys{}, fmt.Errorf("failed to read the dependency graph, error: %s", err)
+	}
+
+	dependencies := map[string]cartfile.ResolvedDependency{}
+	for _, dependency := range resolvedDependencies {
+		dependencies[dependency.Name()] = dependency
+	}
+
+	keys := artifactKeys{keys: map[string]artifactKey{}, skipReasons: map[string]string{}}
+	for _, dependency := range resolvedDependencies {
+		pins, reason, err := cache.dependencyPins(dependency.Name(), dependencies, graph)
+		if err != nil {
+			return artifactKeys{}, err
+		}
+		if reason != "" {
+			keys.skipReasons[dependency.Name()] = reason
+			continue
+		}
+		keys.keys[dependency.Name()] = newArtifactKey(dependency, pins, cache.fingerprint)
+	}
+	return keys, nil
+}
+
+// dependencyPins returns the pins of the transitive dependencies of the dependency, by name,
+// or the reason why the dependency has no artifact key.
+func (cache ArtifactCache) dependencyPins(name string, dependencies map[string]cartfile.ResolvedDependency, graph dependencyGraph) (map[string]string, string, error) {
+	pins := map[string]string{}
+	queue := []string{name}
+	for len(queue) > 0 {
+		dependency := dependencies[queue[0]]
+		queue = queue[1:]
+
+		if !dependency.HasImmutablePin() {
+			if dependency.Name() == name {
+				return nil, "pinned to a branch", nil
+			}
+			return nil, fmt.Sprintf("depends on %s, pinned to a branch", dependency.Name()), nil
+		}
+
+		if dependency.Origin != cartfile.OriginBinary {
+			checkoutDir := filepath.Join(cache.project.checkoutsDir(), dependency.Name())
+			if exists, err := pathutil.IsDirExists(checkoutDir); err != nil {
+				return nil, "", fmt.Errorf("failed to check if checkout exists at %s, error: %s", checkoutDir, err)
+			} else if !exists {
+				return nil, fmt.Sprintf("the checkout of %s is missing", dependency.Name()), nil
+			}
+		}
+
+		for _, next := range graph[dependency.Name()] {
+			if _, visited := pins[next]; !visited && next != name {
+				pins[next] = dependencies[next].Pin
+				queue = append(queue, next)
+			}
+		}
+	}
+	return pins, "", nil
+}
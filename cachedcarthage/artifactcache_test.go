@@ -0,0 +1,200 @@
+package cachedcarthage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-carthage/archivecache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const artifactCacheResolvedFileContent = `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "develop"
+`
+
+func Test_GivenStoredDependency_WhenRestoreCalledInOtherProject_ThenExpectBuildProductsRestored(t *testing.T) {
+	// Given
+	store := archivecache.NewArtifactStore(archivecache.NewLocalStorage(t.TempDir()))
+	fingerprint := Fingerprint{SwiftVersion: "5.5.2", XcodeVersion: "13C100"}
+
+	builtProjectDir := givenArtifactCacheProject(t)
+	givenAlamofireBuildProducts(t, filepath.Join(builtProjectDir, "Carthage", "Build"))
+	givenFile(t, filepath.Join(builtProjectDir, "Carthage", "Build", ".Moya.version"), `{"commitish": "develop"}`)
+	builtProject := NewArtifactCache(NewProject(builtProjectDir), fingerprint, store, DefaultStateProvider{})
+	require.NoError(t, builtProject.Save(nil))
+
+	projectDir := givenArtifactCacheProject(t)
+	artifactCache := NewArtifactCache(NewProject(projectDir), fingerprint, store, DefaultStateProvider{})
+
+	// When
+	restored, missing, err := artifactCache.Restore(nil)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alamofire"}, restored)
+	assert.Equal(t, []string{"Moya"}, missing)
+	buildDir := filepath.Join(projectDir, "Carthage", "Build")
+	assert.FileExists(t, filepath.Join(buildDir, ".Alamofire.version"))
+	assert.FileExists(t, filepath.Join(buildDir, "iOS", "Alamofire.framework", "Alamofire"))
+	assert.FileExists(t, filepath.Join(buildDir, "iOS", "Alamofire.framework.dSYM", "Contents", "Info.plist"))
+	assert.FileExists(t, filepath.Join(buildDir, "Mac", "Static", "Alamofire.framework", "Alamofire"))
+	assert.FileExists(t, filepath.Join(buildDir, "Alamofire.xcframework", "Info.plist"))
+	assert.NoFileExists(t, filepath.Join(buildDir, ".Moya.version"))
+}
+
+func Test_GivenDependencyStoredWithOtherFingerprint_WhenRestoreCalled_ThenExpectNothingRestored(t *testing.T) {
+	// Given
+	store := archivecache.NewArtifactStore(archivecache.NewLocalStorage(t.TempDir()))
+
+	builtProjectDir := givenArtifactCacheProject(t)
+	givenAlamofireBuildProducts(t, filepath.Join(builtProjectDir, "Carthage", "Build"))
+	builtProject := NewArtifactCache(NewProject(builtProjectDir), Fingerprint{XcodeVersion: "13C100"}, store, DefaultStateProvider{})
+	require.NoError(t, builtProject.Save([]string{"Alamofire"}))
+
+	artifactCache := NewArtifactCache(NewProject(givenArtifactCacheProject(t)), Fingerprint{XcodeVersion: "13E113"}, store, DefaultStateProvider{})
+
+	// When
+	restored, missing, err := artifactCache.Restore([]string{"Alamofire"})
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+	assert.Equal(t, []string{"Alamofire"}, missing)
+}
+
+func Test_GivenDependencyStoredWithOtherNestedPin_WhenRestoreCalled_ThenExpectDependencyNotRestored(t *testing.T) {
+	// Given
+	store := archivecache.NewArtifactStore(archivecache.NewLocalStorage(t.TempDir()))
+	fingerprint := Fingerprint{XcodeVersion: "13C100"}
+
+	builtProjectDir := givenProjectWithNestedDependency(t, "6.2.0")
+	givenAlamofireBuildProducts(t, filepath.Join(builtProjectDir, "Carthage", "Build"))
+	builtProject := NewArtifactCache(NewProject(builtProjectDir), fingerprint, store, DefaultStateProvider{})
+	require.NoError(t, builtProject.Save([]string{"Alamofire"}))
+
+	artifactCache := NewArtifactCache(NewProject(givenProjectWithNestedDependency(t, "6.5.0")), fingerprint, store, DefaultStateProvider{})
+
+	// When
+	restored, missing, err := artifactCache.Restore([]string{"Alamofire"})
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+	assert.Equal(t, []string{"Alamofire"}, missing)
+}
+
+func Test_GivenCheckoutIsMissing_WhenRestoreCalled_ThenExpectDependencyNotRestored(t *testing.T) {
+	// Given
+	store := archivecache.NewArtifactStore(archivecache.NewLocalStorage(t.TempDir()))
+	fingerprint := Fingerprint{XcodeVersion: "13C100"}
+
+	builtProjectDir := givenArtifactCacheProject(t)
+	givenAlamofireBuildProducts(t, filepath.Join(builtProjectDir, "Carthage", "Build"))
+	builtProject := NewArtifactCache(NewProject(builtProjectDir), fingerprint, store, DefaultStateProvider{})
+	require.NoError(t, builtProject.Save([]string{"Alamofire"}))
+
+	projectDir := t.TempDir()
+	givenFile(t, filepath.Join(projectDir, "Cartfile.resolved"), artifactCacheResolvedFileContent)
+	artifactCache := NewArtifactCache(NewProject(projectDir), fingerprint, store, DefaultStateProvider{})
+
+	// When
+	restored, missing, err := artifactCache.Restore([]string{"Alamofire"})
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+	assert.Equal(t, []string{"Alamofire"}, missing)
+}
+
+// artifactKeys
+func Test_GivenDependencyOfBranchPinnedDependency_WhenArtifactKeysCalled_ThenExpectNoKey(t *testing.T) {
+	// Given
+	projectDir := givenProjectWithNestedDependency(t, "main")
+	artifactCache := NewArtifactCache(NewProject(projectDir), Fingerprint{}, nil, DefaultStateProvider{})
+	dependencies := givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"
+github "ReactiveX/RxSwift" "main"`)
+
+	// When
+	keys, err := artifactCache.artifactKeys(dependencies)
+
+	// Then
+	require.NoError(t, err)
+	assert.Empty(t, keys.keys)
+	assert.Equal(t, map[string]string{
+		"Alamofire": "depends on RxSwift, pinned to a branch",
+		"RxSwift":   "pinned to a branch",
+	}, keys.skipReasons)
+}
+
+func Test_GivenNestedDependency_WhenArtifactKeysCalled_ThenExpectNestedPinInKey(t *testing.T) {
+	// Given
+	projectDir := givenProjectWithNestedDependency(t, "6.2.0")
+	artifactCache := NewArtifactCache(NewProject(projectDir), Fingerprint{}, nil, DefaultStateProvider{})
+	dependencies := givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"
+github "ReactiveX/RxSwift" "6.2.0"`)
+
+	// When
+	keys, err := artifactCache.artifactKeys(dependencies)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"RxSwift": "6.2.0"}, keys.keys["Alamofire"].DependencyPins)
+	assert.Empty(t, keys.keys["RxSwift"].DependencyPins)
+	assert.Empty(t, keys.skipReasons)
+}
+
+func Test_GivenParseStateFails_WhenRestoreCalled_ThenExpectError(t *testing.T) {
+	// Given
+	stateProvider := new(MockProjectStateProvider).GivenParseStateFails(assert.AnError)
+	artifactCache := NewArtifactCache(NewProject(t.TempDir()), Fingerprint{}, nil, stateProvider)
+
+	// When
+	_, _, err := artifactCache.Restore(nil)
+
+	// Then
+	assert.Error(t, err)
+}
+
+func Test_WhenArtifactKeyStringCalled_ThenExpectNameAndHash(t *testing.T) {
+	// Given
+	key := artifactKey{Dependency: "Alamofire", URL: "https://github.com/Alamofire/Alamofire.git", Pin: "5.4.1"}
+	otherPin := artifactKey{Dependency: "Alamofire", URL: "https://github.com/Alamofire/Alamofire.git", Pin: "5.4.2"}
+	otherNestedPin := artifactKey{Dependency: "Alamofire", URL: "https://github.com/Alamofire/Alamofire.git", Pin: "5.4.1",
+		DependencyPins: map[string]string{"RxSwift": "6.2.0"}}
+
+	// When
+	actual := key.String()
+
+	// Then
+	assert.Regexp(t, `^Alamofire-[0-9a-f]{64}$`, actual)
+	assert.NotEqual(t, actual, otherPin.String())
+	assert.NotEqual(t, actual, otherNestedPin.String())
+}
+
+func givenArtifactCacheProject(t *testing.T) string {
+	projectDir := t.TempDir()
+	givenFile(t, filepath.Join(projectDir, "Cartfile.resolved"), artifactCacheResolvedFileContent)
+	givenFile(t, filepath.Join(projectDir, "Carthage", "Checkouts", "Alamofire", "README.md"), "")
+	givenFile(t, filepath.Join(projectDir, "Carthage", "Checkouts", "Moya", "README.md"), "")
+	return projectDir
+}
+
+func givenProjectWithNestedDependency(t *testing.T, nestedPin string) string {
+	projectDir := t.TempDir()
+	givenFile(t, filepath.Join(projectDir, "Cartfile.resolved"), `github "Alamofire/Alamofire" "5.4.1"
+github "ReactiveX/RxSwift" "`+nestedPin+`"
+`)
+	givenFile(t, filepath.Join(projectDir, "Carthage", "Checkouts", "Alamofire", "Cartfile"), `github "ReactiveX/RxSwift" ~> 6.0`)
+	givenFile(t, filepath.Join(projectDir, "Carthage", "Checkouts", "RxSwift", "README.md"), "")
+	return projectDir
+}
+
+func givenAlamofireBuildProducts(t *testing.T, buildDir string) {
+	givenFile(t, filepath.Join(buildDir, ".Alamofire.version"), alamofireVersionFileContent)
+	givenFile(t, filepath.Join(buildDir, "iOS", "Alamofire.framework", "Alamofire"), "12345")
+	givenFile(t, filepath.Join(buildDir, "iOS", "Alamofire.framework.dSYM", "Contents", "Info.plist"), "")
+	givenFile(t, filepath.Join(buildDir, "Mac", "Static", "Alamofire.framework", "Alamofire"), "123")
+	givenFile(t, filepath.Join(buildDir, "Alamofire.xcframework", "Info.plist"), "")
+	givenFile(t, filepath.Join(buildDir, "Alamofire.xcframework", "tvos-arm64", "Alamofire.framework", "Alamofire"), "123")
+}
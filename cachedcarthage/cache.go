@@ -7,6 +7,7 @@ import (
 
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
 )
 
 // FileCache ...
@@ -15,6 +16,13 @@ type FileCache interface {
 	Commit() error
 }
 
+// RemoteFileCache is a FileCache the step restores from a cache backend itself, instead of the Cache:Pull step.
+type RemoteFileCache interface {
+	FileCache
+	// Location returns where the cache is restored from.
+	Location() string
+}
+
 // ProjectStateProvider ...
 type ProjectStateProvider interface {
 	ParseState(project Project) (ProjectState, error)
@@ -22,22 +30,33 @@ type ProjectStateProvider interface {
 
 // Cache can be used the cache Carthage command results.
 type Cache struct {
-	project       Project
-	swiftVersion  string
-	filecache     FileCache
-	stateProvider ProjectStateProvider
+	project     Project
+	fingerprint Fingerprint
+	// carthageKitDir is the global CarthageKit cache dir, its dependency and binary caches are cached too if set.
+	carthageKitDir string
+	filecache      FileCache
+	stateProvider  ProjectStateProvider
 }
 
 // NewCache ...
-func NewCache(project Project, swiftVersion string, filecache FileCache, stateProvider ProjectStateProvider) Cache {
+func NewCache(project Project, fingerprint Fingerprint, carthageKitDir string, filecache FileCache, stateProvider ProjectStateProvider) Cache {
 	return Cache{
-		project:       project,
-		swiftVersion:  swiftVersion,
-		filecache:     filecache,
-		stateProvider: stateProvider,
+		project:        project,
+		fingerprint:    fingerprint,
+		carthageKitDir: carthageKitDir,
+		filecache:      filecache,
+		stateProvider:  stateProvider,
 	}
 }
 
+// RestoreLocation returns where the cache is restored from, if the step restores it itself.
+func (cache Cache) RestoreLocation() string {
+	if remote, ok := cache.filecache.(RemoteFileCache); ok {
+		return remote.Location()
+	}
+	return ""
+}
+
 // CreateIndicator creates the `Cachefile`.
 func (cache Cache) CreateIndicator() error {
 	state, err := cache.stateProvider.ParseState(cache.project)
@@ -51,7 +70,10 @@ func (cache Cache) CreateIndicator() error {
 		}
 	}
 
-	cacheContent := cache.createContentOfCacheFile(state.resolvedFileContent)
+	cacheContent, err := cache.createContentOfCacheFile(state.resolvedDependencies)
+	if err != nil {
+		return fmt.Errorf("Failed to create cache file content, error: %s", err)
+	}
 	if err := fileutil.WriteStringToFile(cache.project.cacheFilePath(), cacheContent); err != nil {
 		return fmt.Errorf("Failed to write cahe file, error: %s", err)
 	}
@@ -62,16 +84,28 @@ func (cache Cache) CreateIndicator() error {
 
 // Commit includes the Carthage dir if the Cachefile's content changes.
 func (cache Cache) Commit() error {
-	absCarthageDir, err := filepath.Abs(cache.project.carthageDir())
+	paths, err := cache.projectIncludePaths(false)
 	if err != nil {
-		return fmt.Errorf("failed to determine absolute carthage dir")
+		return err
 	}
-	absCacheFilePth, err := filepath.Abs(cache.project.cacheFilePath())
+
+	cache.filecache.IncludePath(cache.withCarthageKitIncludePaths(paths...)...)
+	if err := cache.filecache.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cache paths")
+	}
+
+	return nil
+}
+
+// CommitCheckoutsSeparately includes the Carthage/Build and the Carthage/Checkouts dirs in the cache separately,
+// each with its own indicator, so the checkouts are kept if only the build products change.
+func (cache Cache) CommitCheckoutsSeparately() error {
+	paths, err := cache.projectIncludePaths(true)
 	if err != nil {
-		return fmt.Errorf("failed to determine absolute cachefile path")
+		return err
 	}
 
-	cache.filecache.IncludePath(fmt.Sprintf("%s -> %s", absCarthageDir, absCacheFilePth))
+	cache.filecache.IncludePath(cache.withCarthageKitIncludePaths(paths...)...)
 	if err := cache.filecache.Commit(); err != nil {
 		return fmt.Errorf("failed to commit cache paths")
 	}
@@ -79,9 +113,62 @@ func (cache Cache) Commit() error {
 	return nil
 }
 
+// IncludePaths returns the cache include paths of Commit, or of CommitCheckoutsSeparately if separateCheckouts is set,
+// without writing any indicator or committing the cache.
+func (cache Cache) IncludePaths(separateCheckouts bool) ([]string, error) {
+	paths, err := cache.projectIncludePaths(separateCheckouts)
+	if err != nil {
+		return nil, err
+	}
+	if cache.carthageKitDir == "" {
+		return paths, nil
+	}
+
+	carthageKitPaths, err := cache.carthageKitIncludePaths()
+	if err != nil {
+		return nil, err
+	}
+	return append(paths, carthageKitPaths...), nil
+}
+
+// projectIncludePaths returns the cache include paths of the Carthage dir, or of the Carthage/Build and Carthage/Checkouts dirs
+// with their indicators if separateCheckouts is set.
+func (cache Cache) projectIncludePaths(separateCheckouts bool) ([]string, error) {
+	if !separateCheckouts {
+		absCarthageDir, err := filepath.Abs(cache.project.carthageDir())
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine absolute carthage dir")
+		}
+		absCacheFilePth, err := filepath.Abs(cache.project.cacheFilePath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine absolute cachefile path")
+		}
+		return []string{fmt.Sprintf("%s -> %s", absCarthageDir, absCacheFilePth)}, nil
+	}
+
+	var paths []string
+	for _, pth := range []string{
+		cache.project.buildDir(), cache.project.cacheFilePath(),
+		cache.project.checkoutsDir(), cache.project.checkoutsCacheFilePath(),
+	} {
+		absPth, err := filepath.Abs(pth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine absolute path of %s", pth)
+		}
+		paths = append(paths, absPth)
+	}
+	absBuildDir, absCacheFilePth, absCheckoutsDir, absCheckoutsCacheFilePth := paths[0], paths[1], paths[2], paths[3]
+
+	return []string{
+		fmt.Sprintf("%s -> %s", absBuildDir, absCacheFilePth),
+		absCacheFilePth,
+		fmt.Sprintf("%s -> %s", absCheckoutsDir, absCheckoutsCacheFilePth),
+		absCheckoutsCacheFilePth,
+	}, nil
+}
+
 // IsAvailable returns if the Carthage project has cache available.
 func (cache Cache) IsAvailable() (bool, error) {
-
 	state, err := cache.stateProvider.ParseState(cache.project)
 	if err != nil {
 		return false, err
@@ -93,22 +180,137 @@ func (cache Cache) IsAvailable() (bool, error) {
 		return false, nil
 	}
 
-	expectedCacheFileContent := cache.createContentOfCacheFile(state.resolvedFileContent)
-	if state.cacheFileContent != expectedCacheFileContent {
-		log.Debugf(
-			"Cachefile is not valid.\n" +
-				"Desired cache content:\n" +
-				expectedCacheFileContent +
-				"CacheFile content:\n" +
-				state.cacheFileContent,
-		)
+	diff, err := cache.diffCacheFile(state)
+	if err != nil {
+		log.Printf("Cachefile is not valid: %s", err)
+		return false, nil
+	}
 
+	if len(state.brokenDependencies) > 0 {
+		log.Infof("Cached build products are incomplete:")
+		for _, dependency := range state.brokenDependencies {
+			log.Printf("- %s: %s", dependency.name, dependency.reason)
+		}
+		return false, nil
+	}
+
+	if !diff.isEmpty() {
+		log.Infof("Cachefile is outdated, changes since the cache was created:")
+		for _, change := range append(diff.fingerprintChanges, diff.dependencyChanges...) {
+			log.Printf("- %s", change)
+		}
 		return false, nil
 	}
 
 	return true, nil
 }
 
+// withCarthageKitIncludePaths writes the indicator of the CarthageKit caches and appends their include paths, if they are cached.
+// Failing to include them does not prevent caching the project.
+func (cache Cache) withCarthageKitIncludePaths(paths ...string) []string {
+	if cache.carthageKitDir == "" {
+		return paths
+	}
+
+	if err := cache.writeCarthageKitIndicator(); err != nil {
+		log.Warnf("Failed to include the CarthageKit caches, error: %s", err)
+		return paths
+	}
+	carthageKitPaths, err := cache.carthageKitIncludePaths()
+	if err != nil {
+		log.Warnf("Failed to include the CarthageKit caches, error: %s", err)
+		return paths
+	}
+	return append(paths, carthageKitPaths...)
+}
+
+// CreateCheckoutsIndicator creates the indicator of the Carthage/Checkouts dir, which records the checked out dependencies.
+func (cache Cache) CreateCheckoutsIndicator() error {
+	state, err := cache.stateProvider.ParseState(cache.project)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cache.project.carthageDir(), 0777); err != nil {
+		return fmt.Errorf("failed to create dir (%s), error: %s", cache.project.carthageDir(), err)
+	}
+
+	content, err := createContentOfCheckoutsCacheFile(state.resolvedDependencies)
+	if err != nil {
+		return fmt.Errorf("failed to create %s content, error: %s", checkoutsCacheFileName, err)
+	}
+	if err := fileutil.WriteStringToFile(cache.project.checkoutsCacheFilePath(), content); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", checkoutsCacheFileName, err)
+	}
+
+	log.Donef("%s created: %s", checkoutsCacheFileName, cache.project.checkoutsCacheFilePath())
+	return nil
+}
+
+// IsCheckoutsAvailable tells if the Carthage/Checkouts dir holds the dependencies pinned in the Cartfile.resolved.
+// The checkouts do not depend on the build settings, so they can be reused when the build products can not.
+func (cache Cache) IsCheckoutsAvailable() (bool, error) {
+	state, err := cache.stateProvider.ParseState(cache.project)
+	if err != nil {
+		return false, err
+	}
+
+	if !state.isCheckoutsCacheIntact() {
+		return false, nil
+	}
+
+	recorded, err := parseCacheFile(state.checkoutsCacheFileContent)
+	if err != nil {
+		log.Printf("%s is not valid: %s", checkoutsCacheFileName, err)
+		return false, nil
+	}
+
+	diff := recorded.diff(newCacheFile(Fingerprint{}, state.resolvedDependencies))
+	if !diff.isEmpty() {
+		log.Infof("Checkouts are outdated, changes since the checkouts were cached:")
+		for _, change := range diff.dependencyChanges {
+			log.Printf("- %s", change)
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// OutdatedDependencies returns the names of the resolved dependencies whose cached build products can not be reused,
+// because their pinned version changed since the Cachefile was created or their build products are incomplete.
+// An empty list is returned if the cached build products can not be reused at all and every dependency needs to be built.
+func (cache Cache) OutdatedDependencies() ([]string, error) {
+	state, err := cache.stateProvider.ParseState(cache.project)
+	if err != nil {
+		return nil, err
+	}
+
+	if !state.isCacheIntact() {
+		return nil, nil
+	}
+
+	diff, err := cache.diffCacheFile(state)
+	if err != nil {
+		log.Debugf("Cachefile is not valid, all dependencies need to be rebuilt: %s", err)
+		return nil, nil
+	}
+
+	if len(diff.fingerprintChanges) > 0 {
+		log.Debugf("Build settings changed since the cache was created, all dependencies need to be rebuilt")
+		return nil, nil
+	}
+
+	outdated := diff.outdatedDependencies
+	for _, name := range state.brokenDependencyNames() {
+		if !contains(outdated, name) {
+			outdated = append(outdated, name)
+		}
+	}
+
+	return outdated, nil
+}
+
 func (cache Cache) logProjectStateWarnings(state ProjectState) {
 	// Print the warning about the missing Cachefile only if the other required file (Cartfile.resolved) is available.
 	// If the Cartfile.resolved is not found, then we don't want to mislead the user with this warning.
@@ -125,10 +327,20 @@ func (cache Cache) logProjectStateWarnings(state ProjectState) {
 	}
 }
 
-func (cache Cache) createContentOfCacheFile(resolvedFileContent string) string {
-	return fmt.Sprintf("--Swift version: %s --Swift version \n --%s: %s --%s",
-		cache.swiftVersion,
-		resolvedFileName,
-		resolvedFileContent,
-		resolvedFileName)
+func (cache Cache) createContentOfCacheFile(dependencies []cartfile.ResolvedDependency) (string, error) {
+	return newCacheFile(cache.fingerprint, dependencies).content()
+}
+
+// createContentOfCheckoutsCacheFile records only the dependencies, the checkouts do not depend on the build settings.
+func createContentOfCheckoutsCacheFile(dependencies []cartfile.ResolvedDependency) (string, error) {
+	return newCacheFile(Fingerprint{}, dependencies).content()
+}
+
+func (cache Cache) diffCacheFile(state ProjectState) (cacheFileDiff, error) {
+	recorded, err := parseCacheFile(state.cacheFileContent)
+	if err != nil {
+		return cacheFileDiff{}, err
+	}
+
+	return recorded.diff(newCacheFile(cache.fingerprint, state.resolvedDependencies)), nil
 }
@@ -5,13 +5,61 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// RestoreLocation
+func Test_GivenFileCache_WhenRestoreLocationCalled_ThenExpectLocationOfRemoteFileCache(t *testing.T) {
+	testScenarios := []struct {
+		name      string
+		filecache FileCache
+		expected  string
+	}{
+		{
+			name:      "restored by the Cache:Pull step",
+			filecache: givenMockFileCache(),
+		},
+		{
+			name:      "restored by the step",
+			filecache: stubRemoteFileCache{MockFileCache: givenMockFileCache(), location: "/cache/carthage-cache-key.tar.zst"},
+			expected:  "/cache/carthage-cache-key.tar.zst",
+		},
+		{
+			name:      "synchronized",
+			filecache: NewSynchronizedFileCache(stubRemoteFileCache{MockFileCache: givenMockFileCache(), location: "/cache/carthage-cache-key.tar.zst"}, &sync.Mutex{}),
+			expected:  "/cache/carthage-cache-key.tar.zst",
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// Given
+			cache := Cache{filecache: testScenario.filecache}
+
+			// When
+			location := cache.RestoreLocation()
+
+			// Then
+			assert.Equal(t, testScenario.expected, location)
+		})
+	}
+}
+
+type stubRemoteFileCache struct {
+	*MockFileCache
+	location string
+}
+
+func (cache stubRemoteFileCache) Location() string {
+	return cache.location
+}
+
 // CreateIndicator
 func Test_GivenStateCouldNotBeParsed_WhenCreateIndicatorCalled_ThenExpectError(t *testing.T) {
 	// Given
@@ -21,7 +69,7 @@ func Test_GivenStateCouldNotBeParsed_WhenCreateIndicatorCalled_ThenExpectError(t
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -48,7 +96,7 @@ func Test_GivenCarthageDirDoesNotExist_WhenCreateIndicatorCalled_ThenExpectCarth
 
 	cache := Cache{
 		project:       project,
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -65,30 +113,58 @@ func Test_GivenCarthageDirDoesNotExist_WhenCreateIndicatorCalled_ThenExpectCarth
 // cacheFileContent
 func Test_WhenCacheFileContentCalled_ThenExpectCorrectValue(t *testing.T) {
 	// Given
-	resolvedFileName := "Cartfile.resolved"
-	content := "nice content"
-	swiftVersion := "5.0.2"
+	dependencies := givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"`)
+	fingerprint := Fingerprint{
+		SwiftVersion:    "5.0.2",
+		XcodeVersion:    "Xcode 13.2.1\nBuild version 13C100",
+		SDKVersions:     "iphoneos 15.2",
+		Platforms:       []string{"tvos", "ios"},
+		Configuration:   "Release",
+		UseXCFrameworks: true,
+		XcconfigHash:    "abc",
+	}
 
-	expectedContent := fmt.Sprintf("--Swift version: %s --Swift version \n --%s: %s --%s",
-		swiftVersion,
-		resolvedFileName,
-		content,
-		resolvedFileName)
+	expectedContent := `{
+  "format_version": 2,
+  "fingerprint": {
+    "swift_version": "5.0.2",
+    "xcode_version": "Xcode 13.2.1\nBuild version 13C100",
+    "sdk_versions": "iphoneos 15.2",
+    "platforms": [
+      "ios",
+      "tvos"
+    ],
+    "configuration": "Release",
+    "use_xcframeworks": true,
+    "no_use_binaries": false,
+    "xcconfig_hash": "abc"
+  },
+  "dependencies": [
+    {
+      "name": "Alamofire",
+      "origin": "github",
+      "source": "Alamofire/Alamofire",
+      "pin": "5.4.1"
+    }
+  ]
+}
+`
 
 	mockStateProvider := givenMockProjectStateProvider()
 	mockFileCache := givenMockFileCache()
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  swiftVersion,
+		fingerprint:   fingerprint,
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
 
 	// When
-	actualContent := cache.createContentOfCacheFile(content)
+	actualContent, err := cache.createContentOfCacheFile(dependencies)
 
 	// Then
+	assert.NoError(t, err)
 	assert.Equal(t, expectedContent, actualContent)
 }
 
@@ -102,7 +178,7 @@ func Test_GivenFileCacheCommitFails_WhenCommitCalled_ThenExpectError(t *testing.
 		GivenCommitFails(expectedError)
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -127,7 +203,7 @@ func Test_GivenFileCacheCommitSucceeds_WhenCommitCalled_ThenExpectIncludePathCal
 		GivenCommitSucceeds()
 	cache := Cache{
 		project:       Project{projectDir},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -150,7 +226,7 @@ func Test_GivenStateCouldNotBeParsed_WhenIsAvailableCalled_ThenExpectError(t *te
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -171,7 +247,7 @@ func Test_GivenStateIsNotIntact_WhenIsAvailableCalled_ThenExpectFalse(t *testing
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -197,7 +273,7 @@ func Test_GivenStateIsIntactButCacheFileIsCorrupt_WhenIsAvailableCalled_ThenExpe
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  "whatever",
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -212,29 +288,15 @@ func Test_GivenStateIsIntactButCacheFileIsCorrupt_WhenIsAvailableCalled_ThenExpe
 
 func Test_GivenStateIsIntactAndCacheFileIsCorrect_WhenIsAvailableCalled_ThenExpectTrue(t *testing.T) {
 	// Given
-	resolvedFileName := "Cartfile.resolved"
-	resolvedContent := "nice content"
-	swiftVersion := "5.0.2"
-
-	expectedContent := fmt.Sprintf("--Swift version: %s --Swift version \n --%s: %s --%s",
-		swiftVersion,
-		resolvedFileName,
-		resolvedContent,
-		resolvedFileName)
-
-	state := ProjectState{
-		buildDirNotEmpty:    true,
-		cacheFileExists:     true,
-		cacheFileContent:    expectedContent,
-		resolvedFileExists:  true,
-		resolvedFileContent: resolvedContent,
-	}
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	fingerprint := Fingerprint{SwiftVersion: "5.0.2", XcodeVersion: "13C100"}
+	state := givenIntactProjectState(t, givenCacheFileContent(t, fingerprint, resolvedContent), resolvedContent)
 	mockStateProvider := givenMockProjectStateProvider().GivenParseStateSucceeds(state)
 	mockFileCache := givenMockFileCache()
 
 	cache := Cache{
 		project:       Project{},
-		swiftVersion:  swiftVersion,
+		fingerprint:   fingerprint,
 		filecache:     mockFileCache,
 		stateProvider: mockStateProvider,
 	}
@@ -247,7 +309,294 @@ func Test_GivenStateIsIntactAndCacheFileIsCorrect_WhenIsAvailableCalled_ThenExpe
 	assert.True(t, actualValue)
 }
 
+func Test_GivenLegacyCacheFileWithSameContent_WhenIsAvailableCalled_ThenExpectFalse(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	legacyContent := "--Swift version: 5.0.2 --Swift version \n --Cartfile.resolved: " + resolvedContent + " --Cartfile.resolved"
+	state := givenIntactProjectState(t, legacyContent, resolvedContent)
+
+	cache := Cache{
+		project:       Project{},
+		fingerprint:   Fingerprint{SwiftVersion: "5.0.2", XcodeVersion: "13C100", Platforms: []string{"ios"}},
+		filecache:     givenMockFileCache(),
+		stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(state),
+	}
+
+	// When
+	actualValue, err := cache.IsAvailable()
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, actualValue)
+}
+
+func Test_GivenCacheFileIsCorrectButBuildProductsAreBroken_WhenIsAvailableCalled_ThenExpectFalse(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	fingerprint := Fingerprint{SwiftVersion: "5.0.2"}
+	state := givenIntactProjectState(t, givenCacheFileContent(t, fingerprint, resolvedContent), resolvedContent)
+	state.brokenDependencies = []brokenDependency{{name: "Alamofire", reason: ".Alamofire.version is missing"}}
+
+	cache := Cache{
+		project:       Project{},
+		fingerprint:   fingerprint,
+		filecache:     givenMockFileCache(),
+		stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(state),
+	}
+
+	// When
+	actualValue, err := cache.IsAvailable()
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, actualValue)
+}
+
+// OutdatedDependencies
+func Test_GivenStateIsNotIntact_WhenOutdatedDependenciesCalled_ThenExpectEmptyList(t *testing.T) {
+	// Given
+	mockStateProvider := givenMockProjectStateProvider().GivenParseStateSucceeds(ProjectState{})
+	cache := Cache{
+		project:       Project{},
+		fingerprint:   Fingerprint{SwiftVersion: "whatever"},
+		filecache:     givenMockFileCache(),
+		stateProvider: mockStateProvider,
+	}
+
+	// When
+	actualDependencies, err := cache.OutdatedDependencies()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Empty(t, actualDependencies)
+}
+
+func Test_GivenSwiftVersionChanged_WhenOutdatedDependenciesCalled_ThenExpectEmptyList(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.0"`
+	cache := Cache{
+		project:     Project{},
+		fingerprint: Fingerprint{SwiftVersion: "5.0.1"},
+		filecache:   givenMockFileCache(),
+	}
+	state := givenIntactProjectState(t, givenCacheFileContent(t, Fingerprint{SwiftVersion: "5.0.2"}, resolvedContent), resolvedContent)
+	cache.stateProvider = givenMockProjectStateProvider().GivenParseStateSucceeds(state)
+
+	// When
+	actualDependencies, err := cache.OutdatedDependencies()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Empty(t, actualDependencies)
+}
+
+func Test_GivenLegacyCacheFile_WhenOutdatedDependenciesCalled_ThenExpectEmptyList(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	legacyContent := "--Swift version: 5.0.2 --Swift version \n --Cartfile.resolved: " + resolvedContent + " --Cartfile.resolved"
+	cache := Cache{
+		project:     Project{},
+		fingerprint: Fingerprint{SwiftVersion: "5.0.2"},
+		filecache:   givenMockFileCache(),
+	}
+	state := givenIntactProjectState(t, legacyContent, resolvedContent)
+	cache.stateProvider = givenMockProjectStateProvider().GivenParseStateSucceeds(state)
+
+	// When
+	actualDependencies, err := cache.OutdatedDependencies()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Empty(t, actualDependencies)
+}
+
+func Test_GivenDependencyVersionsChanged_WhenOutdatedDependenciesCalled_ThenExpectChangedDependencies(t *testing.T) {
+	// Given
+	cachedResolvedContent := `github "Alamofire/Alamofire" "5.4.0"
+github "Moya/Moya" "14.0.0"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+`
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+git "https://github.com/ReactiveX/RxSwift.git" "6.2.0"
+`
+	cache := Cache{
+		project:     Project{},
+		fingerprint: Fingerprint{SwiftVersion: "5.0.2"},
+		filecache:   givenMockFileCache(),
+	}
+	state := givenIntactProjectState(t, givenCacheFileContent(t, cache.fingerprint, cachedResolvedContent), resolvedContent)
+	cache.stateProvider = givenMockProjectStateProvider().GivenParseStateSucceeds(state)
+
+	// When
+	actualDependencies, err := cache.OutdatedDependencies()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Alamofire", "RxSwift"}, actualDependencies)
+}
+
+func Test_GivenBrokenDependencies_WhenOutdatedDependenciesCalled_ThenExpectBrokenDependenciesIncluded(t *testing.T) {
+	// Given
+	cachedResolvedContent := `github "Alamofire/Alamofire" "5.4.0"
+github "Moya/Moya" "14.0.0"
+`
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+`
+	cache := Cache{
+		project:     Project{},
+		fingerprint: Fingerprint{SwiftVersion: "5.0.2"},
+		filecache:   givenMockFileCache(),
+	}
+	state := givenIntactProjectState(t, givenCacheFileContent(t, cache.fingerprint, cachedResolvedContent), resolvedContent)
+	state.brokenDependencies = []brokenDependency{
+		{name: "Alamofire", reason: ".Alamofire.version is missing"},
+		{name: "Moya", reason: "missing build products: iOS/Moya.framework"},
+	}
+	cache.stateProvider = givenMockProjectStateProvider().GivenParseStateSucceeds(state)
+
+	// When
+	actualDependencies, err := cache.OutdatedDependencies()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Alamofire", "Moya"}, actualDependencies)
+}
+
 // helpers
+// CommitCheckoutsSeparately
+func Test_GivenFileCacheCommitSucceeds_WhenCommitCheckoutsSeparatelyCalled_ThenExpectBuildAndCheckoutsIncludedSeparately(t *testing.T) {
+	// Given
+	projectDir := "/awesomepath"
+	expectedCacheCall := []string{
+		"/awesomepath/Carthage/Build -> /awesomepath/Carthage/Cachefile",
+		"/awesomepath/Carthage/Cachefile",
+		"/awesomepath/Carthage/Checkouts -> /awesomepath/Carthage/CheckoutsCachefile",
+		"/awesomepath/Carthage/CheckoutsCachefile",
+	}
+	mockFileCache := givenMockFileCache().
+		GivenIncludeSucceeds().
+		GivenCommitSucceeds()
+	cache := Cache{
+		project:       Project{projectDir},
+		filecache:     mockFileCache,
+		stateProvider: givenMockProjectStateProvider(),
+	}
+
+	// When
+	err := cache.CommitCheckoutsSeparately()
+
+	// Then
+	assert.NoError(t, err)
+	mockFileCache.AssertCalled(t, "IncludePath", expectedCacheCall)
+	mockFileCache.AssertCalled(t, "Commit")
+}
+
+// CreateCheckoutsIndicator
+func Test_GivenResolvedDependencies_WhenCreateCheckoutsIndicatorCalled_ThenExpectCheckoutsCacheFileWithoutFingerprint(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	projectDir := givenTempDir(t)
+	cache := Cache{
+		project:       Project{projectDir},
+		fingerprint:   Fingerprint{SwiftVersion: "5.5"},
+		stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(givenIntactProjectState(t, "", resolvedContent)),
+	}
+
+	// When
+	err := cache.CreateCheckoutsIndicator()
+
+	// Then
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(projectDir, "Carthage", "CheckoutsCachefile"))
+	require.NoError(t, err)
+	assert.Equal(t, givenCacheFileContent(t, Fingerprint{}, resolvedContent), string(content))
+}
+
+// IsCheckoutsAvailable
+func Test_GivenCheckoutsMatchResolvedDependencies_WhenIsCheckoutsAvailableCalled_ThenExpectTrue(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	state := givenIntactCheckoutsProjectState(t, givenCacheFileContent(t, Fingerprint{}, resolvedContent), resolvedContent)
+	cache := Cache{
+		fingerprint:   Fingerprint{SwiftVersion: "5.6"},
+		stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(state),
+	}
+
+	// When
+	available, err := cache.IsCheckoutsAvailable()
+
+	// Then
+	assert.NoError(t, err)
+	assert.True(t, available)
+}
+
+func Test_GivenCheckoutsOutdated_WhenIsCheckoutsAvailableCalled_ThenExpectFalse(t *testing.T) {
+	// Given
+	state := givenIntactCheckoutsProjectState(t,
+		givenCacheFileContent(t, Fingerprint{}, `github "Alamofire/Alamofire" "5.4.0"`),
+		`github "Alamofire/Alamofire" "5.4.1"`)
+	cache := Cache{stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(state)}
+
+	// When
+	available, err := cache.IsCheckoutsAvailable()
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, available)
+}
+
+func Test_GivenNoCheckouts_WhenIsCheckoutsAvailableCalled_ThenExpectFalse(t *testing.T) {
+	// Given
+	resolvedContent := `github "Alamofire/Alamofire" "5.4.1"`
+	state := givenIntactCheckoutsProjectState(t, givenCacheFileContent(t, Fingerprint{}, resolvedContent), resolvedContent)
+	state.checkoutsDirNotEmpty = false
+	cache := Cache{stateProvider: givenMockProjectStateProvider().GivenParseStateSucceeds(state)}
+
+	// When
+	available, err := cache.IsCheckoutsAvailable()
+
+	// Then
+	assert.NoError(t, err)
+	assert.False(t, available)
+}
+
+func givenResolvedDependencies(t *testing.T, resolvedFileContent string) []cartfile.ResolvedDependency {
+	resolvedFile, err := cartfile.ParseResolved(resolvedFileContent)
+	require.NoError(t, err)
+	return resolvedFile.Dependencies
+}
+
+func givenCacheFileContent(t *testing.T, fingerprint Fingerprint, resolvedFileContent string) string {
+	content, err := newCacheFile(fingerprint, givenResolvedDependencies(t, resolvedFileContent)).content()
+	require.NoError(t, err)
+	return content
+}
+
+func givenIntactProjectState(t *testing.T, cacheFileContent, resolvedFileContent string) ProjectState {
+	return ProjectState{
+		buildDirNotEmpty:     true,
+		cacheFileExists:      true,
+		cacheFileContent:     cacheFileContent,
+		resolvedFileExists:   true,
+		resolvedFileContent:  resolvedFileContent,
+		resolvedDependencies: givenResolvedDependencies(t, resolvedFileContent),
+	}
+}
+
+func givenIntactCheckoutsProjectState(t *testing.T, checkoutsCacheFileContent, resolvedFileContent string) ProjectState {
+	return ProjectState{
+		checkoutsDirNotEmpty:      true,
+		checkoutsCacheFileExists:  true,
+		checkoutsCacheFileContent: checkoutsCacheFileContent,
+		resolvedFileExists:        true,
+		resolvedFileContent:       resolvedFileContent,
+		resolvedDependencies:      givenResolvedDependencies(t, resolvedFileContent),
+	}
+}
+
 func givenMockProjectStateProvider() *MockProjectStateProvider {
 	return new(MockProjectStateProvider)
 }
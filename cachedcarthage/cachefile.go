@@ -0,0 +1,175 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+const (
+	legacyCacheFileFormatVersion = 1
+	cacheFileFormatVersion       = 2
+)
+
+// cacheFile is the model of the Cachefile, which records what the cached build products were built from.
+type cacheFile struct {
+	FormatVersion int                `json:"format_version"`
+	Fingerprint   Fingerprint        `json:"fingerprint"`
+	Dependencies  []cachedDependency `json:"dependencies"`
+}
+
+type cachedDependency struct {
+	Name   string          `json:"name"`
+	Origin cartfile.Origin `json:"origin"`
+	Source string          `json:"source"`
+	Pin    string          `json:"pin"`
+}
+
+// cacheFileDiff lists the differences between a recorded and an expected Cachefile.
+type cacheFileDiff struct {
+	fingerprintChanges []string
+	dependencyChanges  []string
+	// outdatedDependencies are the expected dependencies which are missing or pinned differently in the recorded Cachefile.
+	outdatedDependencies []string
+}
+
+func newCacheFile(fingerprint Fingerprint, dependencies []cartfile.ResolvedDependency) cacheFile {
+	file := cacheFile{
+		FormatVersion: cacheFileFormatVersion,
+		Fingerprint:   fingerprint.normalized(),
+		Dependencies:  []cachedDependency{},
+	}
+	for _, dependency := range dependencies {
+		file.Dependencies = append(file.Dependencies, cachedDependency{
+			Name:   dependency.Name(),
+			Origin: dependency.Origin,
+			Source: dependency.Source,
+			Pin:    dependency.Pin,
+		})
+	}
+	return file
+}
+
+func (file cacheFile) content() (string, error) {
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// parseCacheFile parses the Cachefile content, migrating the legacy `--<name>: <value> --<name>` format if needed.
+// The legacy Cachefile recorded only the Swift version and the Cartfile.resolved, so it never matches the expected one (see diff).
+func parseCacheFile(content string) (cacheFile, error) {
+	if !strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return parseLegacyCacheFile(content)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal([]byte(content), &file); err != nil {
+		return cacheFile{}, fmt.Errorf("invalid %s: %s", cacheFileName, err)
+	}
+	if file.FormatVersion != cacheFileFormatVersion {
+		return cacheFile{}, fmt.Errorf("unsupported %s format version: %d", cacheFileName, file.FormatVersion)
+	}
+	return file, nil
+}
+
+func parseLegacyCacheFile(content string) (cacheFile, error) {
+	resolvedFileContent, ok := cacheFileSegment(content, resolvedFileName)
+	if !ok {
+		return cacheFile{}, fmt.Errorf("invalid %s: %s is not recorded", cacheFileName, resolvedFileName)
+	}
+	resolvedFile, err := cartfile.ParseResolved(resolvedFileContent)
+	if err != nil {
+		return cacheFile{}, fmt.Errorf("invalid %s: %s", cacheFileName, err)
+	}
+
+	swiftVersion, _ := cacheFileSegment(content, swiftVersionSegment)
+
+	file := newCacheFile(Fingerprint{SwiftVersion: swiftVersion}, resolvedFile.Dependencies)
+	file.FormatVersion = legacyCacheFileFormatVersion
+	return file, nil
+}
+
+// cacheFileSegment returns the value of a `--<name>: <value> --<name>` segment of a legacy Cachefile.
+func cacheFileSegment(content, name string) (string, bool) {
+	startMarker := "--" + name + ": "
+	endMarker := " --" + name
+
+	start := strings.Index(content, startMarker)
+	if start == -1 {
+		return "", false
+	}
+	start += len(startMarker)
+
+	end := strings.LastIndex(content, endMarker)
+	if end < start {
+		return "", false
+	}
+
+	return content[start:end], true
+}
+
+// diff compares the recorded Cachefile to the expected one.
+// A legacy Cachefile does not record the build environment the cache was built in, so it is reported as a fingerprint change:
+// its cache is missed once and all dependencies are rebuilt, after which the Cachefile is recreated in the current format.
+func (file cacheFile) diff(expected cacheFile) cacheFileDiff {
+	var diff cacheFileDiff
+
+	if file.FormatVersion == legacyCacheFileFormatVersion {
+		diff.fingerprintChanges = append(diff.fingerprintChanges,
+			fmt.Sprintf("%s format changed from %d to %d, the build environment was not recorded", cacheFileName, file.FormatVersion, expected.FormatVersion))
+	} else {
+		recordedComponents := file.Fingerprint.components()
+		for i, component := range expected.Fingerprint.components() {
+			if recorded := recordedComponents[i].value; recorded != component.value {
+				diff.fingerprintChanges = append(diff.fingerprintChanges,
+					fmt.Sprintf("%s changed from %s to %s", component.name, readableValue(recorded), readableValue(component.value)))
+			}
+		}
+	}
+
+	recordedDependencies := map[string]cachedDependency{}
+	for _, dependency := range file.Dependencies {
+		recordedDependencies[dependency.Name] = dependency
+	}
+	expectedDependencies := map[string]bool{}
+	for _, dependency := range expected.Dependencies {
+		expectedDependencies[dependency.Name] = true
+
+		recorded, ok := recordedDependencies[dependency.Name]
+		switch {
+		case !ok:
+			diff.dependencyChanges = append(diff.dependencyChanges, fmt.Sprintf("%s %s added", dependency.Name, dependency.Pin))
+		case recorded.Origin != dependency.Origin || recorded.Source != dependency.Source:
+			diff.dependencyChanges = append(diff.dependencyChanges, fmt.Sprintf("%s source changed from %s %s to %s %s",
+				dependency.Name, recorded.Origin, recorded.Source, dependency.Origin, dependency.Source))
+		case recorded.Pin != dependency.Pin:
+			diff.dependencyChanges = append(diff.dependencyChanges, fmt.Sprintf("%s %s -> %s", dependency.Name, recorded.Pin, dependency.Pin))
+		default:
+			continue
+		}
+		diff.outdatedDependencies = append(diff.outdatedDependencies, dependency.Name)
+	}
+	for _, dependency := range file.Dependencies {
+		if !expectedDependencies[dependency.Name] {
+			diff.dependencyChanges = append(diff.dependencyChanges, fmt.Sprintf("%s %s removed", dependency.Name, dependency.Pin))
+		}
+	}
+
+	return diff
+}
+
+func (diff cacheFileDiff) isEmpty() bool {
+	return len(diff.fingerprintChanges) == 0 && len(diff.dependencyChanges) == 0
+}
+
+func readableValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return strings.Replace(value, "\n", " ", -1)
+}
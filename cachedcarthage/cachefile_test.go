@@ -0,0 +1,119 @@
+package cachedcarthage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseCacheFile
+func Test_GivenCacheFileContent_WhenParseCacheFileCalled_ThenExpectRoundTrip(t *testing.T) {
+	// Given
+	expected := newCacheFile(
+		Fingerprint{SwiftVersion: "5.5", Platforms: []string{"ios"}, UseXCFrameworks: true},
+		givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"`),
+	)
+	content, err := expected.content()
+	require.NoError(t, err)
+
+	// When
+	actual, err := parseCacheFile(content)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_GivenUnsupportedFormatVersion_WhenParseCacheFileCalled_ThenExpectError(t *testing.T) {
+	// When
+	_, err := parseCacheFile(`{"format_version": 3}`)
+
+	// Then
+	assert.EqualError(t, err, "unsupported Cachefile format version: 3")
+}
+
+func Test_GivenCorruptCacheFile_WhenParseCacheFileCalled_ThenExpectError(t *testing.T) {
+	// When
+	_, err := parseCacheFile("corrupt")
+
+	// Then
+	assert.Error(t, err)
+}
+
+func Test_GivenLegacyCacheFile_WhenParseCacheFileCalled_ThenExpectMigratedCacheFile(t *testing.T) {
+	// Given
+	content := "--Swift version: 5.0.2 --Swift version \n " +
+		"--Cartfile.resolved: github \"Alamofire/Alamofire\" \"5.4.1\"\n --Cartfile.resolved"
+
+	// When
+	actual, err := parseCacheFile(content)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, legacyCacheFileFormatVersion, actual.FormatVersion)
+	assert.Equal(t, Fingerprint{SwiftVersion: "5.0.2"}.normalized(), actual.Fingerprint)
+	assert.Equal(t, []cachedDependency{{Name: "Alamofire", Origin: "github", Source: "Alamofire/Alamofire", Pin: "5.4.1"}}, actual.Dependencies)
+}
+
+// diff
+func Test_GivenChangedCacheFile_WhenDiffCalled_ThenExpectFieldByFieldChanges(t *testing.T) {
+	// Given
+	recorded := newCacheFile(
+		Fingerprint{SwiftVersion: "5.5", Platforms: []string{"ios"}},
+		givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.0"
+github "Moya/Moya" "14.0.0"
+github "Quick/Nimble" "9.0.0"
+`),
+	)
+	expected := newCacheFile(
+		Fingerprint{SwiftVersion: "5.6", Platforms: []string{"ios"}},
+		givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+github "ReactiveX/RxSwift" "6.2.0"
+`),
+	)
+
+	// When
+	diff := recorded.diff(expected)
+
+	// Then
+	assert.Equal(t, []string{"Swift version changed from 5.5 to 5.6"}, diff.fingerprintChanges)
+	assert.Equal(t, []string{
+		"Alamofire 5.4.0 -> 5.4.1",
+		"RxSwift 6.2.0 added",
+		"Nimble 9.0.0 removed",
+	}, diff.dependencyChanges)
+	assert.Equal(t, []string{"Alamofire", "RxSwift"}, diff.outdatedDependencies)
+	assert.False(t, diff.isEmpty())
+}
+
+func Test_GivenLegacyCacheFile_WhenDiffCalled_ThenExpectFormatChange(t *testing.T) {
+	// Given
+	recorded, err := parseCacheFile("--Swift version: 5.0.2 --Swift version \n --Cartfile.resolved: github \"Alamofire/Alamofire\" \"5.4.1\" --Cartfile.resolved")
+	require.NoError(t, err)
+	expected := newCacheFile(
+		Fingerprint{SwiftVersion: "5.0.2", XcodeVersion: "13C100", Platforms: []string{"ios"}, UseXCFrameworks: true},
+		givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"`),
+	)
+
+	// When
+	diff := recorded.diff(expected)
+
+	// Then
+	assert.Equal(t, []string{"Cachefile format changed from 1 to 2, the build environment was not recorded"}, diff.fingerprintChanges)
+	assert.Empty(t, diff.dependencyChanges)
+	assert.False(t, diff.isEmpty())
+}
+
+func Test_GivenSameCacheFile_WhenDiffCalled_ThenExpectEmptyDiff(t *testing.T) {
+	// Given
+	recorded := newCacheFile(Fingerprint{Platforms: []string{"ios", "tvos"}}, givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.0"`))
+	expected := newCacheFile(Fingerprint{Platforms: []string{"tvos", "ios"}}, givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.0"`))
+
+	// When
+	diff := recorded.diff(expected)
+
+	// Then
+	assert.True(t, diff.isEmpty())
+}
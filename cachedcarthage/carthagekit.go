@@ -0,0 +1,91 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+const (
+	// CarthageKit keeps the git mirrors of the dependencies and the downloaded binaries
+	// in these sub-directories of its global cache dir (~/Library/Caches/org.carthage.CarthageKit).
+	carthageKitDependenciesDirName = "dependencies"
+	carthageKitBinariesDirName     = "binaries"
+
+	carthageKitCacheFileFormatVersion = 1
+)
+
+// carthageKitCacheFile is the indicator of the CarthageKit caches, which records the URLs of the cached dependencies.
+// The mirrors and binaries are kept for every version, so the caches need to be updated only if the set of dependencies changes.
+type carthageKitCacheFile struct {
+	FormatVersion  int      `json:"format_version"`
+	DependencyURLs []string `json:"dependency_urls"`
+}
+
+func newCarthageKitCacheFile(dependencies []cartfile.ResolvedDependency) carthageKitCacheFile {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, dependency := range dependencies {
+		if url := dependency.URL(); !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	sort.Strings(urls)
+
+	return carthageKitCacheFile{
+		FormatVersion:  carthageKitCacheFileFormatVersion,
+		DependencyURLs: urls,
+	}
+}
+
+func (file carthageKitCacheFile) content() (string, error) {
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// writeCarthageKitIndicator writes the indicator of the CarthageKit caches.
+func (cache Cache) writeCarthageKitIndicator() error {
+	state, err := cache.stateProvider.ParseState(cache.project)
+	if err != nil {
+		return err
+	}
+
+	content, err := newCarthageKitCacheFile(state.resolvedDependencies).content()
+	if err != nil {
+		return fmt.Errorf("failed to create %s content, error: %s", carthageKitCacheFileName, err)
+	}
+	if err := fileutil.WriteStringToFile(cache.project.carthageKitCacheFilePath(), content); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", carthageKitCacheFileName, err)
+	}
+	return nil
+}
+
+// carthageKitIncludePaths returns the cache include paths of the existing CarthageKit cache dirs.
+func (cache Cache) carthageKitIncludePaths() ([]string, error) {
+	absCacheFilePth, err := filepath.Abs(cache.project.carthageKitCacheFilePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine absolute %s path", carthageKitCacheFileName)
+	}
+
+	var paths []string
+	for _, name := range []string{carthageKitDependenciesDirName, carthageKitBinariesDirName} {
+		dir := filepath.Join(cache.carthageKitDir, name)
+		if exists, err := pathutil.IsDirExists(dir); err != nil {
+			return nil, err
+		} else if !exists {
+			continue
+		}
+		paths = append(paths, fmt.Sprintf("%s -> %s", dir, absCacheFilePth))
+	}
+
+	return paths, nil
+}
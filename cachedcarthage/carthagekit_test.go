@@ -0,0 +1,118 @@
+package cachedcarthage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenResolvedDependencies_WhenNewCarthageKitCacheFileCalled_ThenExpectSortedUniqueURLs(t *testing.T) {
+	// Given
+	dependencies := givenResolvedDependencies(t, `github "ReactiveX/RxSwift" "6.2.0"
+github "Alamofire/Alamofire" "5.4.1"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+`)
+
+	// When
+	actual := newCarthageKitCacheFile(dependencies)
+
+	// Then
+	assert.Equal(t, []string{
+		"https://dl.google.com/FirebaseAnalyticsBinary.json",
+		"https://github.com/Alamofire/Alamofire.git",
+		"https://github.com/ReactiveX/RxSwift.git",
+	}, actual.DependencyURLs)
+}
+
+func Test_GivenPinChanged_WhenNewCarthageKitCacheFileCalled_ThenExpectSameContent(t *testing.T) {
+	// Given
+	before, err := newCarthageKitCacheFile(givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.0"`)).content()
+	require.NoError(t, err)
+
+	// When
+	after, err := newCarthageKitCacheFile(givenResolvedDependencies(t, `github "Alamofire/Alamofire" "5.4.1"`)).content()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func Test_GivenCarthageKitDir_WhenCommitCalled_ThenExpectExistingCarthageKitCachesIncluded(t *testing.T) {
+	// Given
+	projectDir := givenTempDir(t)
+	carthageKitDir := givenTempDir(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(carthageKitDir, "dependencies"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(projectDir, "Carthage"), 0777))
+	cacheFilePath := filepath.Join(projectDir, "Carthage", "CarthageKitCachefile")
+	mockFileCache := givenMockFileCache().
+		GivenIncludeSucceeds().
+		GivenCommitSucceeds()
+	cache := Cache{
+		project:        Project{projectDir},
+		carthageKitDir: carthageKitDir,
+		filecache:      mockFileCache,
+		stateProvider:  givenMockProjectStateProvider().GivenParseStateSucceeds(givenIntactProjectState(t, "", `github "Alamofire/Alamofire" "5.4.1"`)),
+	}
+
+	// When
+	err := cache.Commit()
+
+	// Then
+	assert.NoError(t, err)
+	mockFileCache.AssertCalled(t, "IncludePath", []string{
+		filepath.Join(projectDir, "Carthage") + " -> " + filepath.Join(projectDir, "Carthage", "Cachefile"),
+		filepath.Join(carthageKitDir, "dependencies") + " -> " + cacheFilePath,
+	})
+	assert.FileExists(t, cacheFilePath)
+}
+
+func Test_GivenStateCouldNotBeParsed_WhenCommitCalledWithCarthageKitDir_ThenExpectProjectCommitted(t *testing.T) {
+	// Given
+	mockFileCache := givenMockFileCache().
+		GivenIncludeSucceeds().
+		GivenCommitSucceeds()
+	cache := Cache{
+		project:        Project{"/awesomepath"},
+		carthageKitDir: "/carthagekit",
+		filecache:      mockFileCache,
+		stateProvider:  givenMockProjectStateProvider().GivenParseStateFails(errors.New("sad error")),
+	}
+
+	// When
+	err := cache.Commit()
+
+	// Then
+	assert.NoError(t, err)
+	mockFileCache.AssertCalled(t, "IncludePath", []string{"/awesomepath/Carthage -> /awesomepath/Carthage/Cachefile"})
+}
+
+func Test_GivenCarthageKitDir_WhenIncludePathsCalled_ThenExpectPathsWithoutIndicatorWritten(t *testing.T) {
+	// Given
+	projectDir := givenTempDir(t)
+	carthageKitDir := givenTempDir(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(carthageKitDir, "binaries"), 0777))
+	cacheFilePath := filepath.Join(projectDir, "Carthage", "CarthageKitCachefile")
+	cache := Cache{
+		project:        Project{projectDir},
+		carthageKitDir: carthageKitDir,
+		stateProvider:  givenMockProjectStateProvider(),
+	}
+
+	// When
+	paths, err := cache.IncludePaths(true)
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(projectDir, "Carthage", "Build") + " -> " + filepath.Join(projectDir, "Carthage", "Cachefile"),
+		filepath.Join(projectDir, "Carthage", "Cachefile"),
+		filepath.Join(projectDir, "Carthage", "Checkouts") + " -> " + filepath.Join(projectDir, "Carthage", "CheckoutsCachefile"),
+		filepath.Join(projectDir, "Carthage", "CheckoutsCachefile"),
+		filepath.Join(carthageKitDir, "binaries") + " -> " + cacheFilePath,
+	}, paths)
+	assert.NoFileExists(t, cacheFilePath)
+}
@@ -1,21 +1,28 @@
 package cachedcarthage
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
 )
 
+// errSchemeFound stops looking for shared schemes at the first one.
+var errSchemeFound = errors.New("shared scheme found")
+
 // DefaultStateProvider reads the current state of a cached Carthage project.
 type DefaultStateProvider struct {
 }
 
 // ParseState ...
 func (provider DefaultStateProvider) ParseState(project Project) (ProjectState, error) {
-	buildDirExists, buildDirFiles := provider.parseBuildDirectoryState(project.buildDir())
+	buildDirExists, buildDirFiles := provider.parseDirectoryState(project.buildDir())
 	cacheFileExists, cacheFileContent, err := provider.parseCacheFileState(project.cacheFilePath())
 	if err != nil {
 		return ProjectState{}, err
@@ -24,27 +31,52 @@ func (provider DefaultStateProvider) ParseState(project Project) (ProjectState,
 	if err != nil {
 		return ProjectState{}, err
 	}
+	resolvedFile, err := cartfile.ParseResolved(resolvedFileContent)
+	if err != nil {
+		return ProjectState{}, fmt.Errorf("failed to parse %s, error: %s", resolvedFileName, err)
+	}
 
 	carthageDirExists, err := pathutil.IsPathExists(project.carthageDir())
 	if err != nil {
 		return ProjectState{}, fmt.Errorf("failed to check if dir exists at (%s), error: %s", project.carthageDir(), err)
 	}
 
+	checkoutsDirExists, checkoutsDirFiles := provider.parseDirectoryState(project.checkoutsDir())
+	checkoutsCacheFileExists, checkoutsCacheFileContent, err := provider.parseCacheFileState(project.checkoutsCacheFilePath())
+	if err != nil {
+		return ProjectState{}, err
+	}
+
+	var brokenDependencies []brokenDependency
+	if buildDirExists && len(buildDirFiles) != 0 {
+		brokenDependencies, err = provider.parseBrokenDependencies(project, resolvedFile.Dependencies)
+		if err != nil {
+			return ProjectState{}, err
+		}
+	}
+
 	return ProjectState{
 		buildDirNotEmpty: buildDirExists && len(buildDirFiles) != 0,
 
 		cacheFileExists:  cacheFileExists,
 		cacheFileContent: cacheFileContent,
 
-		resolvedFileExists:  resolvedFileExists,
-		resolvedFileContent: resolvedFileContent,
+		resolvedFileExists:   resolvedFileExists,
+		resolvedFileContent:  resolvedFileContent,
+		resolvedDependencies: resolvedFile.Dependencies,
 
 		carthageDirExists: carthageDirExists,
+
+		checkoutsDirNotEmpty:      checkoutsDirExists && len(checkoutsDirFiles) != 0,
+		checkoutsCacheFileExists:  checkoutsCacheFileExists,
+		checkoutsCacheFileContent: checkoutsCacheFileContent,
+
+		brokenDependencies: brokenDependencies,
 	}, nil
 }
 
-func (provider DefaultStateProvider) parseBuildDirectoryState(buildDir string) (bool, []os.FileInfo) {
-	files, err := ioutil.ReadDir(buildDir)
+func (provider DefaultStateProvider) parseDirectoryState(dir string) (bool, []os.FileInfo) {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return false, nil
 	}
@@ -84,6 +116,100 @@ func (provider DefaultStateProvider) parseResolvedFileState(path string) (bool,
 	return true, resolvedFileContent, nil
 }
 
+// parseBrokenDependencies validates the build products of every resolved dependency against its version file.
+// A dependency without a version file is not broken if Carthage does not build it, as its checkout has no shared schemes
+// (like a repository of xcconfig files or scripts).
+func (provider DefaultStateProvider) parseBrokenDependencies(project Project, dependencies []cartfile.ResolvedDependency) ([]brokenDependency, error) {
+	var broken []brokenDependency
+	for _, dependency := range dependencies {
+		built, err := isBuiltByCarthage(project, dependency.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !built {
+			continue
+		}
+
+		reason, err := checkBuildProducts(project.buildDir(), dependency)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			broken = append(broken, brokenDependency{name: dependency.Name(), reason: reason})
+		}
+	}
+
+	return broken, nil
+}
+
+// isBuiltByCarthage returns false if the dependency has no version file and its checkout has no shared schemes.
+// A dependency without a checkout (like a binary one) is expected to be built.
+func isBuiltByCarthage(project Project, dependencyName string) (bool, error) {
+	if exists, err := pathutil.IsPathExists(filepath.Join(project.buildDir(), versionFileName(dependencyName))); err != nil {
+		return false, err
+	} else if exists {
+		return true, nil
+	}
+
+	checkoutDir := filepath.Join(project.checkoutsDir(), dependencyName)
+	if exists, err := pathutil.IsDirExists(checkoutDir); err != nil {
+		return false, err
+	} else if !exists {
+		return true, nil
+	}
+
+	return hasSharedSchemes(checkoutDir)
+}
+
+// hasSharedSchemes returns true if the dir contains a shared Xcode scheme (xcshareddata/xcschemes/*.xcscheme),
+// outside of its own Carthage dir, like Carthage looks for the schemes to build.
+func hasSharedSchemes(dir string) (bool, error) {
+	found := false
+	err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && pth == filepath.Join(dir, carthageDirName) {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && filepath.Ext(pth) == ".xcscheme" && strings.HasSuffix(filepath.Dir(pth), filepath.Join("xcshareddata", "xcschemes")) {
+			found = true
+			return errSchemeFound
+		}
+		return nil
+	})
+	if err != nil && err != errSchemeFound {
+		return false, fmt.Errorf("failed to look for shared schemes in %s, error: %s", dir, err)
+	}
+	return found, nil
+}
+
+// checkBuildProducts returns why the build products of the dependency do not match its pinned version, empty if they do.
+func checkBuildProducts(buildDir string, dependency cartfile.ResolvedDependency) (string, error) {
+	file, exists, err := readVersionFile(buildDir, dependency.Name())
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	if !exists {
+		return versionFileName(dependency.Name()) + " is missing", nil
+	}
+
+	if file.Commitish != dependency.Pin {
+		return fmt.Sprintf("%s records %s instead of %s", versionFileName(dependency.Name()), file.Commitish, dependency.Pin), nil
+	}
+
+	missing, err := file.missingBuildProducts(buildDir)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("missing build products: %s", strings.Join(missing, ", ")), nil
+	}
+
+	return "", nil
+}
+
 func (provider DefaultStateProvider) contentOfFile(pth string) (string, error) {
 	if exist, err := pathutil.IsPathExists(pth); err != nil {
 		return "", err
@@ -0,0 +1,59 @@
+package cachedcarthage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ParseState
+func Test_GivenBuildDirWithBrokenDependencies_WhenParseStateCalled_ThenExpectBrokenDependencies(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	buildDir := filepath.Join(projectDir, "Carthage", "Build")
+	givenFile(t, filepath.Join(projectDir, "Cartfile.resolved"), `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "15.0.0"
+github "Quick/Nimble" "9.2.1"
+github "ReactiveX/RxSwift" "6.2.0"
+`)
+	givenFile(t, filepath.Join(buildDir, ".Alamofire.version"), alamofireVersionFileContent)
+	givenFile(t, filepath.Join(buildDir, ".Moya.version"), `{"commitish": "14.0.0", "iOS": []}`)
+	givenFile(t, filepath.Join(buildDir, ".RxSwift.version"), `{"commitish": "6.2.0", "iOS": [{"name": "RxSwift", "linking": "dynamic"}]}`)
+	require.NoError(t, os.MkdirAll(filepath.Join(buildDir, "iOS", "RxSwift.framework"), 0777))
+
+	// When
+	state, err := DefaultStateProvider{}.ParseState(NewProject(projectDir))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []brokenDependency{
+		{name: "Alamofire", reason: "missing build products: Mac/Static/Alamofire.framework, iOS/Alamofire.framework, Alamofire.xcframework/tvos-arm64"},
+		{name: "Moya", reason: ".Moya.version records 14.0.0 instead of 15.0.0"},
+		{name: "Nimble", reason: ".Nimble.version is missing"},
+	}, state.brokenDependencies)
+}
+
+func Test_GivenDependencyWithoutVersionFile_WhenParseStateCalled_ThenExpectBrokenOnlyIfCheckoutHasSharedSchemes(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	carthageDir := filepath.Join(projectDir, "Carthage")
+	givenFile(t, filepath.Join(projectDir, "Cartfile.resolved"), `github "Quick/Nimble" "9.2.1"
+github "jspahrsummers/xcconfigs" "1.1"
+`)
+	givenFile(t, filepath.Join(carthageDir, "Build", ".Alamofire.version"), `{"commitish": "5.4.1"}`)
+	givenFile(t, filepath.Join(carthageDir, "Checkouts", "Nimble", "Nimble.xcodeproj", "xcshareddata", "xcschemes", "Nimble-iOS.xcscheme"), "<Scheme/>")
+	givenFile(t, filepath.Join(carthageDir, "Checkouts", "xcconfigs", "Base", "Common.xcconfig"), "ONLY_ACTIVE_ARCH = NO")
+	givenFile(t, filepath.Join(carthageDir, "Checkouts", "xcconfigs", "Carthage", "Checkouts", "Nested", "Nested.xcodeproj", "xcshareddata", "xcschemes", "Nested.xcscheme"), "<Scheme/>")
+
+	// When
+	state, err := DefaultStateProvider{}.ParseState(NewProject(projectDir))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []brokenDependency{
+		{name: "Nimble", reason: ".Nimble.version is missing"},
+	}, state.brokenDependencies)
+}
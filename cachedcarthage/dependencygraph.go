@@ -0,0 +1,14 @@
+package cachedcarthage
+
+// dependencyGraph maps every dependency of the project to the dependencies it depends on.
+type dependencyGraph map[string][]string
+
+// dependencyGraph returns the dependencies of the project's Cartfile.resolved, each with the dependencies it depends on
+// according to its checkout (see CollectResolvedGraph).
+func (project Project) dependencyGraph() (dependencyGraph, error) {
+	graph, err := CollectResolvedGraph(project)
+	if err != nil {
+		return nil, err
+	}
+	return graph.dependencyGraph(), nil
+}
@@ -0,0 +1,108 @@
+package cachedcarthage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenCheckoutsWithCartfiles_WhenDependencyGraphCalled_ThenExpectDependenciesOfCheckouts(t *testing.T) {
+	// Given
+	project := givenProjectWithCheckouts(t, `github "ReactiveX/RxSwift" "6.2.0"
+github "Moya/Moya" "15.0.0"
+github "Alamofire/Alamofire" "5.4.1"
+github "Quick/Quick" "4.0.0"
+github "Quick/Nimble" "9.2.0"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+`, map[string]givenCheckout{
+		"Moya": {
+			cartfile: `github "Alamofire/Alamofire" ~> 5.4
+github "ReactiveX/RxSwift" ~> 6.1
+github "ReactiveCocoa/ReactiveSwift" ~> 6.6
+`,
+			resolvedFile: `github "Alamofire/Alamofire" "5.4.0"
+github "ReactiveX/RxSwift" "6.1.0"
+github "ReactiveCocoa/ReactiveSwift" "6.6.0"
+`,
+		},
+		"RxSwift": {},
+		// The test dependencies of Quick and Nimble depend on each other
+		"Quick":  {resolvedFile: `github "Quick/Nimble" "9.2.0"`},
+		"Nimble": {resolvedFile: `github "Quick/Quick" "4.0.0"`},
+	})
+
+	// When
+	graph, err := project.dependencyGraph()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, dependencyGraph{
+		"RxSwift":                 nil,
+		"Moya":                    {"Alamofire", "RxSwift"},
+		"Alamofire":               nil,
+		"Quick":                   nil,
+		"Nimble":                  nil,
+		"FirebaseAnalyticsBinary": nil,
+	}, graph)
+}
+
+func Test_GivenProjectNamedLikeItsDependency_WhenDependencyGraphCalled_ThenExpectDependenciesOfCheckoutKept(t *testing.T) {
+	// Given
+	project := Project{filepath.Join(t.TempDir(), "Moya")}
+	require.NoError(t, os.MkdirAll(filepath.Join(project.checkoutsDir(), "Moya"), 0777))
+	require.NoError(t, ioutil.WriteFile(project.resolvedFilePath(), []byte(`github "Moya/Moya" "15.0.0"
+github "Alamofire/Alamofire" "5.4.1"
+`), 0666))
+	require.NoError(t, ioutil.WriteFile(project.cartfilePath(), []byte(`github "Moya/Moya" ~> 15.0`), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(project.checkoutsDir(), "Moya", cartfileName), []byte(`github "Alamofire/Alamofire" ~> 5.4`), 0666))
+
+	// When
+	graph, err := project.dependencyGraph()
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, dependencyGraph{
+		"Moya":      {"Alamofire"},
+		"Alamofire": nil,
+	}, graph)
+}
+
+func Test_GivenInvalidResolvedFileOfCheckout_WhenDependencyGraphCalled_ThenExpectError(t *testing.T) {
+	// Given
+	project := givenProjectWithCheckouts(t, `github "Moya/Moya" "15.0.0"`, map[string]givenCheckout{
+		"Moya": {resolvedFile: `github "Alamofire/Alamofire"`},
+	})
+
+	// When
+	_, err := project.dependencyGraph()
+
+	// Then
+	assert.Error(t, err)
+}
+
+// givenCheckout is the content of the Cartfile and the Cartfile.resolved of a checkout, an empty file is not created.
+type givenCheckout struct {
+	cartfile     string
+	resolvedFile string
+}
+
+// givenProjectWithCheckouts creates a project with the Cartfile.resolved, and a checkout for every entry of checkouts.
+func givenProjectWithCheckouts(t *testing.T, resolvedFileContent string, checkouts map[string]givenCheckout) Project {
+	project := Project{t.TempDir()}
+	require.NoError(t, ioutil.WriteFile(project.resolvedFilePath(), []byte(resolvedFileContent), 0666))
+	for name, checkout := range checkouts {
+		dir := filepath.Join(project.checkoutsDir(), name)
+		require.NoError(t, os.MkdirAll(dir, 0777))
+		if checkout.cartfile != "" {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, cartfileName), []byte(checkout.cartfile), 0666))
+		}
+		if checkout.resolvedFile != "" {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, resolvedFileName), []byte(checkout.resolvedFile), 0666))
+		}
+	}
+	return project
+}
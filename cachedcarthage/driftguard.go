@@ -0,0 +1,217 @@
+package cachedcarthage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+const resolvedDiffFileName = "carthage-resolved-diff.json"
+
+// ResolvedDriftMode tells what to do when the update command changes the pins of dependencies outside the allow-list.
+type ResolvedDriftMode string
+
+// Resolved drift modes.
+const (
+	ResolvedDriftFail     ResolvedDriftMode = "fail"
+	ResolvedDriftReport   ResolvedDriftMode = "report"
+	ResolvedDriftDisabled ResolvedDriftMode = "no"
+)
+
+// Resolved change types.
+const (
+	ResolvedChangeAdded   = "added"
+	ResolvedChangeRemoved = "removed"
+	ResolvedChangeUpdated = "updated"
+)
+
+// DriftGuard compares the Cartfile.resolved before and after the update command.
+type DriftGuard struct {
+	Mode ResolvedDriftMode
+	// AllowedDependencies may change, every dependency may change if empty.
+	AllowedDependencies []string
+	StateProvider       ProjectStateProvider
+}
+
+// NewDriftGuard ...
+func NewDriftGuard(mode ResolvedDriftMode, allowedDependencies []string, stateProvider ProjectStateProvider) DriftGuard {
+	return DriftGuard{
+		Mode:                mode,
+		AllowedDependencies: allowedDependencies,
+		StateProvider:       stateProvider,
+	}
+}
+
+// ResolvedDiff lists the changes of the Cartfile.resolved made by the update command.
+type ResolvedDiff struct {
+	Changes []ResolvedChange `json:"changes"`
+}
+
+// ResolvedChange is a dependency added to, removed from, or pinned at a different version in the Cartfile.resolved.
+type ResolvedChange struct {
+	Dependency string `json:"dependency"`
+	Change     string `json:"change"`
+	Before     string `json:"before,omitempty"`
+	After      string `json:"after,omitempty"`
+	// Allowed is false if the dependency is not on the allow-list.
+	Allowed bool `json:"allowed"`
+}
+
+// ChangedDependencies ...
+func (diff ResolvedDiff) ChangedDependencies() []string {
+	var names []string
+	for _, change := range diff.Changes {
+		names = append(names, change.Dependency)
+	}
+	return names
+}
+
+func (diff ResolvedDiff) unexpectedDependencies() []string {
+	var names []string
+	for _, change := range diff.Changes {
+		if !change.Allowed {
+			names = append(names, change.Dependency)
+		}
+	}
+	return names
+}
+
+// diffResolvedDependencies returns the changes between the pinned dependencies, in the order of the new Cartfile.resolved,
+// followed by the removed dependencies.
+func diffResolvedDependencies(before, after []cartfile.ResolvedDependency, allowedDependencies []string) ResolvedDiff {
+	beforePins := map[string]string{}
+	for _, dependency := range before {
+		beforePins[dependency.Name()] = dependency.Pin
+	}
+	afterPins := map[string]string{}
+	for _, dependency := range after {
+		afterPins[dependency.Name()] = dependency.Pin
+	}
+
+	diff := ResolvedDiff{Changes: []ResolvedChange{}}
+	for _, dependency := range after {
+		name := dependency.Name()
+		beforePin, existed := beforePins[name]
+		switch {
+		case !existed:
+			diff.Changes = append(diff.Changes, ResolvedChange{Dependency: name, Change: ResolvedChangeAdded, After: dependency.Pin})
+		case beforePin != dependency.Pin:
+			diff.Changes = append(diff.Changes, ResolvedChange{Dependency: name, Change: ResolvedChangeUpdated, Before: beforePin, After: dependency.Pin})
+		}
+	}
+	for _, dependency := range before {
+		if _, exists := afterPins[dependency.Name()]; !exists {
+			diff.Changes = append(diff.Changes, ResolvedChange{Dependency: dependency.Name(), Change: ResolvedChangeRemoved, Before: dependency.Pin})
+		}
+	}
+
+	for i, change := range diff.Changes {
+		diff.Changes[i].Allowed = len(allowedDependencies) == 0 || contains(allowedDependencies, change.Dependency)
+	}
+	return diff
+}
+
+func (runner Runner) isDriftGuarded() bool {
+	return runner.carthageCommand == updateCommand && runner.driftGuard.Mode != "" && runner.driftGuard.Mode != ResolvedDriftDisabled
+}
+
+// snapshotResolvedDependencies returns the dependencies pinned in the Cartfile.resolved.
+func (runner Runner) snapshotResolvedDependencies() ([]cartfile.ResolvedDependency, error) {
+	state, err := runner.driftGuard.StateProvider.ParseState(runner.project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, error: %s", resolvedFileName, err)
+	}
+	return state.resolvedDependencies, nil
+}
+
+// checkResolvedDrift compares the Cartfile.resolved with the snapshot taken before the update command, and prints the changes.
+// It fails in fail mode, if dependencies outside the allow-list changed.
+func (runner Runner) checkResolvedDrift(before []cartfile.ResolvedDependency) (*ResolvedDiff, error) {
+	after, err := runner.snapshotResolvedDependencies()
+	if err != nil {
+		if runner.driftGuard.Mode == ResolvedDriftFail {
+			return nil, err
+		}
+		log.Warnf("Failed to compare %s, error: %s", resolvedFileName, err)
+		return nil, nil
+	}
+
+	diff := diffResolvedDependencies(before, after, runner.driftGuard.AllowedDependencies)
+	printResolvedDiff(diff)
+
+	unexpected := diff.unexpectedDependencies()
+	if len(unexpected) == 0 {
+		return &diff, nil
+	}
+
+	message := fmt.Sprintf("%s changed for dependencies outside the allowed ones (%s): %s",
+		resolvedFileName, runner.allowedDependenciesDescription(), strings.Join(unexpected, ", "))
+	if runner.driftGuard.Mode == ResolvedDriftFail {
+		return &diff, fmt.Errorf("%s", message)
+	}
+	log.Warnf("%s", message)
+	return &diff, nil
+}
+
+func (runner Runner) allowedDependenciesDescription() string {
+	if len(runner.driftGuard.AllowedDependencies) == 0 {
+		return "every dependency"
+	}
+	return strings.Join(runner.driftGuard.AllowedDependencies, ", ")
+}
+
+func printResolvedDiff(diff ResolvedDiff) {
+	fmt.Println()
+	if len(diff.Changes) == 0 {
+		log.Donef("%s did not change", resolvedFileName)
+		return
+	}
+
+	log.Infof("%s changes:", resolvedFileName)
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Dependency\tChange\tBefore\tAfter")
+	for _, change := range diff.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Dependency, change.Change, orDash(change.Before), orDash(change.After))
+	}
+	_ = w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	log.Printf("%s", lines[0])
+	for i, change := range diff.Changes {
+		if change.Allowed {
+			log.Printf("%s", lines[i+1])
+		} else {
+			log.Errorf("%s", lines[i+1])
+		}
+	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// exportResolvedDiff writes the diff as JSON into the deploy dir, and returns the path of the file.
+func (runner Runner) exportResolvedDiff(diff ResolvedDiff) (string, error) {
+	content, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	pth := filepath.Join(runner.deployDir, resolvedDiffFileName)
+	if err := ioutil.WriteFile(pth, content, 0666); err != nil {
+		return "", fmt.Errorf("failed to write %s, error: %s", pth, err)
+	}
+	return pth, nil
+}
@@ -0,0 +1,142 @@
+package cachedcarthage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	resolvedBeforeUpdate = `github "Alamofire/Alamofire" "5.4.0"
+github "Moya/Moya" "14.0.0"
+github "ReactiveX/RxSwift" "6.1.0"
+`
+	resolvedAfterUpdate = `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+github "Quick/Nimble" "9.2.0"
+`
+)
+
+func Test_GivenResolvedDependencies_WhenDiffResolvedDependenciesCalled_ThenExpectChanges(t *testing.T) {
+	testScenarios := []struct {
+		name                string
+		allowedDependencies []string
+		expected            []ResolvedChange
+	}{
+		{
+			name: "every dependency allowed",
+			expected: []ResolvedChange{
+				{Dependency: "Alamofire", Change: ResolvedChangeUpdated, Before: "5.4.0", After: "5.4.1", Allowed: true},
+				{Dependency: "Nimble", Change: ResolvedChangeAdded, After: "9.2.0", Allowed: true},
+				{Dependency: "RxSwift", Change: ResolvedChangeRemoved, Before: "6.1.0", Allowed: true},
+			},
+		},
+		{
+			name:                "allow-list",
+			allowedDependencies: []string{"Alamofire", "Moya"},
+			expected: []ResolvedChange{
+				{Dependency: "Alamofire", Change: ResolvedChangeUpdated, Before: "5.4.0", After: "5.4.1", Allowed: true},
+				{Dependency: "Nimble", Change: ResolvedChangeAdded, After: "9.2.0", Allowed: false},
+				{Dependency: "RxSwift", Change: ResolvedChangeRemoved, Before: "6.1.0", Allowed: false},
+			},
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// When
+			diff := diffResolvedDependencies(givenResolvedDependencies(t, resolvedBeforeUpdate), givenResolvedDependencies(t, resolvedAfterUpdate), testScenario.allowedDependencies)
+
+			// Then
+			assert.Equal(t, testScenario.expected, diff.Changes)
+		})
+	}
+}
+
+func Test_GivenUpdateChangesOnlyAllowedDependencies_WhenRunCalled_ThenExpectDiffExported(t *testing.T) {
+	// Given
+	deployDir := givenTempDir(t)
+	resolvedAfterAlamofireUpdate := `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+github "ReactiveX/RxSwift" "6.1.0"
+`
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "update",
+		commandBuilder:  givenStubbedCommandBuilder(),
+		driftGuard:      NewDriftGuard(ResolvedDriftFail, []string{"Alamofire"}, givenResolvedStateProvider(t, resolvedBeforeUpdate, resolvedAfterAlamofireUpdate)),
+		project:         NewProject("/base/dir"),
+		deployDir:       deployDir,
+		outputExporter:  mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	require.NoError(t, err)
+	diffPth := filepath.Join(deployDir, resolvedDiffFileName)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", ResolvedChangedDependenciesOutputKey, "Alamofire")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", ResolvedDiffPathOutputKey, diffPth)
+	content, err := ioutil.ReadFile(diffPth)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"change": "updated"`)
+}
+
+func Test_GivenUpdateChangesOtherDependencies_WhenRunCalled_ThenExpectError(t *testing.T) {
+	testScenarios := []struct {
+		name        string
+		mode        ResolvedDriftMode
+		expectedErr string
+	}{
+		{
+			name:        "fail mode",
+			mode:        ResolvedDriftFail,
+			expectedErr: "Cartfile.resolved changed for dependencies outside the allowed ones (Alamofire): Nimble, RxSwift",
+		},
+		{
+			name: "report mode",
+			mode: ResolvedDriftReport,
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// Given
+			mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+			runner := Runner{
+				carthageCommand: "update",
+				commandBuilder:  givenStubbedCommandBuilder(),
+				driftGuard:      NewDriftGuard(testScenario.mode, []string{"Alamofire"}, givenResolvedStateProvider(t, resolvedBeforeUpdate, resolvedAfterUpdate)),
+				project:         NewProject("/base/dir"),
+				outputExporter:  mockOutputExporter,
+			}
+
+			// When
+			err := runner.Run()
+
+			// Then
+			if testScenario.expectedErr != "" {
+				require.EqualError(t, err, testScenario.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			mockOutputExporter.AssertCalled(t, "ExportOutput", ResolvedChangedDependenciesOutputKey, "Alamofire,Nimble,RxSwift")
+		})
+	}
+}
+
+func givenResolvedStateProvider(t *testing.T, resolvedFileContents ...string) *MockProjectStateProvider {
+	var states []ProjectState
+	for _, content := range resolvedFileContents {
+		states = append(states, ProjectState{
+			resolvedFileExists:   true,
+			resolvedFileContent:  content,
+			resolvedDependencies: givenResolvedDependencies(t, content),
+		})
+	}
+	return givenMockProjectStateProvider().GivenParseStateSucceedsInOrder(states...)
+}
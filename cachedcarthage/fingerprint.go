@@ -0,0 +1,69 @@
+package cachedcarthage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	swiftVersionSegment    = "Swift version"
+	xcodeVersionSegment    = "Xcode version"
+	sdkVersionsSegment     = "SDK versions"
+	platformsSegment       = "Platforms"
+	configurationSegment   = "Configuration"
+	useXCFrameworksSegment = "Use XCFrameworks"
+	noUseBinariesSegment   = "No use binaries"
+	xcconfigHashSegment    = "Xcconfig hash"
+)
+
+// Fingerprint describes the toolchain and build settings the cached build products were built with.
+// Build products are reused only if every component of the fingerprint matches.
+type Fingerprint struct {
+	SwiftVersion    string   `json:"swift_version"`
+	XcodeVersion    string   `json:"xcode_version"`
+	SDKVersions     string   `json:"sdk_versions"`
+	Platforms       []string `json:"platforms"`
+	Configuration   string   `json:"configuration"`
+	UseXCFrameworks bool     `json:"use_xcframeworks"`
+	NoUseBinaries   bool     `json:"no_use_binaries"`
+	XcconfigHash    string   `json:"xcconfig_hash"`
+}
+
+type fingerprintComponent struct {
+	name  string
+	value string
+}
+
+// normalized returns the fingerprint with its platforms sorted, so the platform order does not matter.
+func (fingerprint Fingerprint) normalized() Fingerprint {
+	platforms := append([]string{}, fingerprint.Platforms...)
+	sort.Strings(platforms)
+	fingerprint.Platforms = platforms
+	return fingerprint
+}
+
+func (fingerprint Fingerprint) components() []fingerprintComponent {
+	fingerprint = fingerprint.normalized()
+
+	return []fingerprintComponent{
+		{swiftVersionSegment, fingerprint.SwiftVersion},
+		{xcodeVersionSegment, fingerprint.XcodeVersion},
+		{sdkVersionsSegment, fingerprint.SDKVersions},
+		{platformsSegment, strings.Join(fingerprint.Platforms, ",")},
+		{configurationSegment, fingerprint.Configuration},
+		{useXCFrameworksSegment, strconv.FormatBool(fingerprint.UseXCFrameworks)},
+		{noUseBinariesSegment, strconv.FormatBool(fingerprint.NoUseBinaries)},
+		{xcconfigHashSegment, fingerprint.XcconfigHash},
+	}
+}
+
+// Hash returns a stable hash of the fingerprint, which identifies the cache of the build products built with it.
+func (fingerprint Fingerprint) Hash() string {
+	// a struct of strings and booleans always marshals
+	content, _ := json.Marshal(fingerprint.normalized())
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
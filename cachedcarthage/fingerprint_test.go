@@ -0,0 +1,22 @@
+package cachedcarthage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintHash(t *testing.T) {
+	// Given
+	fingerprint := Fingerprint{SwiftVersion: "5.5.2", XcodeVersion: "13C100", Platforms: []string{"tvOS", "iOS"}}
+	reordered := Fingerprint{SwiftVersion: "5.5.2", XcodeVersion: "13C100", Platforms: []string{"iOS", "tvOS"}}
+	changed := Fingerprint{SwiftVersion: "5.5.2", XcodeVersion: "13E113", Platforms: []string{"iOS", "tvOS"}}
+
+	// When
+	hash := fingerprint.Hash()
+
+	// Then
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, reordered.Hash())
+	assert.NotEqual(t, hash, changed.Hash())
+}
@@ -0,0 +1,159 @@
+package cachedcarthage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Build product types.
+const (
+	FrameworkProductType   = "framework"
+	XCFrameworkProductType = "xcframework"
+)
+
+// BuildInventory lists the build products of a Carthage project's Carthage/Build dir.
+type BuildInventory struct {
+	BuildDir string         `json:"build_dir"`
+	Products []BuildProduct `json:"products"`
+}
+
+// BuildProduct is a framework or an xcframework slice built for a dependency, as listed in the dependency's version file.
+type BuildProduct struct {
+	Dependency string `json:"dependency"`
+	Version    string `json:"version"`
+	Platform   string `json:"platform"`
+	Type       string `json:"type"`
+	Linking    string `json:"linking"`
+	// Path is relative to the build dir.
+	Path string `json:"path"`
+	// Architectures are parsed from the xcframework library identifier, version files do not list them for plain frameworks.
+	Architectures []string `json:"architectures"`
+	HasDSYM       bool     `json:"has_dsym"`
+	// HasBCSymbolMap is true if the xcframework slice has BCSymbolMaps, or for plain frameworks,
+	// if the platform dir has any (these are named after the binary UUIDs and can not be matched to a framework).
+	HasBCSymbolMap bool  `json:"has_bcsymbolmap"`
+	SizeBytes      int64 `json:"size_bytes"`
+	Exists         bool  `json:"exists"`
+}
+
+// CollectBuildInventory walks the build dir of the project and collects the build products of every version file.
+// The inventory is empty if the project has no build dir.
+func CollectBuildInventory(project Project) (BuildInventory, error) {
+	buildDir := project.buildDir()
+	inventory := BuildInventory{BuildDir: buildDir, Products: []BuildProduct{}}
+
+	entries, err := ioutil.ReadDir(buildDir)
+	if os.IsNotExist(err) {
+		return inventory, nil
+	} else if err != nil {
+		return BuildInventory{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), ".") || !strings.HasSuffix(entry.Name(), ".version") {
+			continue
+		}
+
+		dependencyName := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), "."), ".version")
+		file, _, err := readVersionFile(buildDir, dependencyName)
+		if err != nil {
+			return BuildInventory{}, err
+		}
+
+		for _, platform := range file.sortedPlatforms() {
+			for _, framework := range file.Platforms[platform] {
+				inventory.Products = append(inventory.Products, collectBuildProduct(buildDir, dependencyName, file.Commitish, platform, framework))
+			}
+		}
+	}
+
+	return inventory, nil
+}
+
+func collectBuildProduct(buildDir, dependencyName, version, platform string, framework versionFileFramework) BuildProduct {
+	productPath := framework.buildProductPath(platform)
+	product := BuildProduct{
+		Dependency: dependencyName,
+		Version:    version,
+		Platform:   platform,
+		Type:       FrameworkProductType,
+		Linking:    framework.Linking,
+		Path:       productPath,
+	}
+
+	var dsymPath, bcSymbolMapDir string
+	if framework.Container != "" {
+		sliceDir := filepath.Join(buildDir, productPath)
+		product.Type = XCFrameworkProductType
+		product.Architectures = parseLibraryIdentifierArchitectures(framework.LibraryIdentifier)
+		dsymPath = filepath.Join(sliceDir, "dSYMs", framework.Name+".framework.dSYM")
+		bcSymbolMapDir = filepath.Join(sliceDir, "BCSymbolMaps")
+	} else {
+		dsymPath = filepath.Join(buildDir, productPath+".dSYM")
+		bcSymbolMapDir = filepath.Join(buildDir, platform)
+	}
+
+	product.HasDSYM = pathExists(dsymPath)
+	product.HasBCSymbolMap = hasFileWithExtension(bcSymbolMapDir, ".bcsymbolmap")
+	product.SizeBytes, product.Exists = sizeOf(filepath.Join(buildDir, productPath))
+
+	return product
+}
+
+// parseLibraryIdentifierArchitectures parses the architectures of an xcframework library identifier,
+// like arm64 and x86_64 of ios-arm64_x86_64-simulator.
+func parseLibraryIdentifierArchitectures(libraryIdentifier string) []string {
+	components := strings.Split(libraryIdentifier, "-")
+	if len(components) < 2 {
+		return nil
+	}
+
+	var architectures []string
+	parts := strings.Split(components[1], "_")
+	for i := 0; i < len(parts); i++ {
+		// x86_64 and arm64_32 contain the separator
+		if i+1 < len(parts) && (parts[i] == "x86" && parts[i+1] == "64" || parts[i] == "arm64" && parts[i+1] == "32") {
+			architectures = append(architectures, parts[i]+"_"+parts[i+1])
+			i++
+			continue
+		}
+		architectures = append(architectures, parts[i])
+	}
+	return architectures
+}
+
+func pathExists(pth string) bool {
+	_, err := os.Stat(pth)
+	return err == nil
+}
+
+func hasFileWithExtension(dir, extension string) bool {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == extension {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeOf returns the total size of the files under the given path and whether the path exists.
+func sizeOf(pth string) (int64, bool) {
+	if !pathExists(pth) {
+		return 0, false
+	}
+
+	var size int64
+	_ = filepath.Walk(pth, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, true
+}
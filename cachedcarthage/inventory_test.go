@@ -0,0 +1,55 @@
+package cachedcarthage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CollectBuildInventory
+func Test_GivenBuildDir_WhenCollectBuildInventoryCalled_ThenExpectProductsOfVersionFiles(t *testing.T) {
+	// Given
+	projectDir := t.TempDir()
+	buildDir := filepath.Join(projectDir, "Carthage", "Build")
+	givenFile(t, filepath.Join(buildDir, ".Alamofire.version"), alamofireVersionFileContent)
+	givenFile(t, filepath.Join(buildDir, "iOS", "Alamofire.framework", "Alamofire"), "12345")
+	givenFile(t, filepath.Join(buildDir, "iOS", "Alamofire.framework.dSYM", "Contents", "Info.plist"), "")
+	givenFile(t, filepath.Join(buildDir, "iOS", "1A2B.bcsymbolmap"), "")
+	givenFile(t, filepath.Join(buildDir, "Alamofire.xcframework", "tvos-arm64", "Alamofire.framework", "Alamofire"), "123")
+	givenFile(t, filepath.Join(buildDir, "Alamofire.xcframework", "tvos-arm64", "dSYMs", "Alamofire.framework.dSYM", "Contents", "Info.plist"), "")
+
+	// When
+	inventory, err := CollectBuildInventory(NewProject(projectDir))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, buildDir, inventory.BuildDir)
+	assert.Equal(t, []BuildProduct{
+		{Dependency: "Alamofire", Version: "5.4.1", Platform: "Mac", Type: "framework", Linking: "static", Path: "Mac/Static/Alamofire.framework"},
+		{Dependency: "Alamofire", Version: "5.4.1", Platform: "iOS", Type: "framework", Linking: "dynamic", Path: "iOS/Alamofire.framework",
+			HasDSYM: true, HasBCSymbolMap: true, SizeBytes: 5, Exists: true},
+		{Dependency: "Alamofire", Version: "5.4.1", Platform: "tvOS", Type: "xcframework", Linking: "dynamic", Path: "Alamofire.xcframework/tvos-arm64",
+			Architectures: []string{"arm64"}, HasDSYM: true, SizeBytes: 3, Exists: true},
+	}, inventory.Products)
+}
+
+func Test_WhenParseLibraryIdentifierArchitecturesCalled_ThenExpectArchitectures(t *testing.T) {
+	testScenarios := map[string][]string{
+		"ios-arm64_armv7":            {"arm64", "armv7"},
+		"ios-arm64_x86_64-simulator": {"arm64", "x86_64"},
+		"watchos-arm64_32_armv7k":    {"arm64_32", "armv7k"},
+		"macos-arm64_x86_64":         {"arm64", "x86_64"},
+		"ios-x86_64-maccatalyst":     {"x86_64"},
+		"unknown":                    nil,
+	}
+
+	for libraryIdentifier, expected := range testScenarios {
+		// When
+		actual := parseLibraryIdentifierArchitectures(libraryIdentifier)
+
+		// Then
+		assert.Equal(t, expected, actual, libraryIdentifier)
+	}
+}
@@ -25,6 +25,38 @@ func (m *MockCarthageCache) IsAvailable() (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+// OutdatedDependencies provides a mock function with given fields:
+func (m *MockCarthageCache) OutdatedDependencies() ([]string, error) {
+	args := m.Called()
+	dependencies, _ := args.Get(0).([]string)
+	return dependencies, args.Error(1)
+}
+
+// CommitCheckoutsSeparately provides a mock function with given fields:
+func (m *MockCarthageCache) CommitCheckoutsSeparately() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// CreateCheckoutsIndicator provides a mock function with given fields:
+func (m *MockCarthageCache) CreateCheckoutsIndicator() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// IsCheckoutsAvailable provides a mock function with given fields:
+func (m *MockCarthageCache) IsCheckoutsAvailable() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+
+// IncludePaths provides a mock function with given fields: separateCheckouts
+func (m *MockCarthageCache) IncludePaths(separateCheckouts bool) ([]string, error) {
+	args := m.Called(separateCheckouts)
+	paths, _ := args.Get(0).([]string)
+	return paths, args.Error(1)
+}
+
 func (m *MockCarthageCache) GivenIsAvailableFails(reason error) *MockCarthageCache {
 	m.On("IsAvailable").Return(false, reason)
 	return m
@@ -54,3 +86,33 @@ func (m *MockCarthageCache) GivenCreateIndicatorSucceeds() *MockCarthageCache {
 	m.On("CreateIndicator").Return(nil)
 	return m
 }
+
+func (m *MockCarthageCache) GivenOutdatedDependenciesFails(reason error) *MockCarthageCache {
+	m.On("OutdatedDependencies").Return(nil, reason)
+	return m
+}
+
+func (m *MockCarthageCache) GivenOutdatedDependenciesSucceeds(dependencies []string) *MockCarthageCache {
+	m.On("OutdatedDependencies").Return(dependencies, nil)
+	return m
+}
+
+func (m *MockCarthageCache) GivenCommitCheckoutsSeparatelySucceeds() *MockCarthageCache {
+	m.On("CommitCheckoutsSeparately").Return(nil)
+	return m
+}
+
+func (m *MockCarthageCache) GivenCreateCheckoutsIndicatorSucceeds() *MockCarthageCache {
+	m.On("CreateCheckoutsIndicator").Return(nil)
+	return m
+}
+
+func (m *MockCarthageCache) GivenIsCheckoutsAvailableSucceeds(result bool) *MockCarthageCache {
+	m.On("IsCheckoutsAvailable").Return(result, nil)
+	return m
+}
+
+func (m *MockCarthageCache) GivenIncludePathsSucceeds(paths []string) *MockCarthageCache {
+	m.On("IncludePaths", mock.Anything).Return(paths, nil)
+	return m
+}
@@ -23,6 +23,12 @@ func (m *MockCommandBuilder) AddXCConfigFile(path string) CommandBuilder {
 	return args.Get(0).(CommandBuilder)
 }
 
+// AddEnvs provides a mock function with given fields: envs
+func (m *MockCommandBuilder) AddEnvs(envs ...string) CommandBuilder {
+	args := m.Called(envs)
+	return args.Get(0).(CommandBuilder)
+}
+
 // Append provides a mock function with given fields: args
 func (m *MockCommandBuilder) Append(args ...string) CommandBuilder {
 	ret := m.Called(args)
@@ -45,6 +51,11 @@ func (m *MockCommandBuilder) GivenAddXCConfigFileSucceeds() *MockCommandBuilder
 	return m
 }
 
+func (m *MockCommandBuilder) GivenAddEnvsSucceeds() *MockCommandBuilder {
+	m.On("AddEnvs", mock.Anything).Return(m)
+	return m
+}
+
 func (m *MockCommandBuilder) GivenAppendSucceeds() *MockCommandBuilder {
 	m.On("Append", mock.Anything).Return(m)
 	return m
@@ -0,0 +1,37 @@
+package cachedcarthage
+
+import "github.com/stretchr/testify/mock"
+
+// MockDependencyArtifactCache is an autogenerated mock type for the DependencyArtifactCache type
+type MockDependencyArtifactCache struct {
+	mock.Mock
+}
+
+// Restore provides a mock function with given fields: dependencies
+func (m *MockDependencyArtifactCache) Restore(dependencies []string) ([]string, []string, error) {
+	args := m.Called(dependencies)
+	restored, _ := args.Get(0).([]string)
+	missing, _ := args.Get(1).([]string)
+	return restored, missing, args.Error(2)
+}
+
+// Save provides a mock function with given fields: dependencies
+func (m *MockDependencyArtifactCache) Save(dependencies []string) error {
+	args := m.Called(dependencies)
+	return args.Error(0)
+}
+
+func (m *MockDependencyArtifactCache) GivenRestoreSucceeds(restored, missing []string) *MockDependencyArtifactCache {
+	m.On("Restore", mock.Anything).Return(restored, missing, nil)
+	return m
+}
+
+func (m *MockDependencyArtifactCache) GivenRestoreFails(reason error) *MockDependencyArtifactCache {
+	m.On("Restore", mock.Anything).Return(nil, nil, reason)
+	return m
+}
+
+func (m *MockDependencyArtifactCache) GivenSaveSucceeds() *MockDependencyArtifactCache {
+	m.On("Save", mock.Anything).Return(nil)
+	return m
+}
@@ -22,3 +22,10 @@ func (m *MockProjectStateProvider) GivenParseStateSucceeds(projectState ProjectS
 	m.On("ParseState", mock.Anything).Return(projectState, nil)
 	return m
 }
+
+func (m *MockProjectStateProvider) GivenParseStateSucceedsInOrder(projectStates ...ProjectState) *MockProjectStateProvider {
+	for _, projectState := range projectStates {
+		m.On("ParseState", mock.Anything).Return(projectState, nil).Once()
+	}
+	return m
+}
@@ -0,0 +1,28 @@
+package cachedcarthage
+
+import (
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRequirementChecker is an autogenerated mock type for the RequirementChecker type
+type MockRequirementChecker struct {
+	mock.Mock
+}
+
+// Check provides a mock function with given fields:
+func (m *MockRequirementChecker) Check() ([]cartfile.CheckResult, error) {
+	args := m.Called()
+	results, _ := args.Get(0).([]cartfile.CheckResult)
+	return results, args.Error(1)
+}
+
+func (m *MockRequirementChecker) GivenCheckFails(reason error) *MockRequirementChecker {
+	m.On("Check").Return(nil, reason)
+	return m
+}
+
+func (m *MockRequirementChecker) GivenCheckSucceeds(results []cartfile.CheckResult) *MockRequirementChecker {
+	m.On("Check").Return(results, nil)
+	return m
+}
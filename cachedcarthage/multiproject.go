@@ -0,0 +1,268 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const projectsSummaryFileName = "carthage-projects.json"
+
+// ProjectResult is the result of the Runner of a project, run by RunProjects.
+type ProjectResult struct {
+	ProjectDir string
+	Duration   time.Duration
+	Err        error
+
+	result              runResult
+	rebuiltDependencies []string
+	buildDir            string
+}
+
+// projectSummary is the result of a project in the JSON summary of RunProjects.
+type projectSummary struct {
+	ProjectDir           string   `json:"project_dir"`
+	CacheHit             bool     `json:"cache_hit"`
+	RebuiltDependencies  []string `json:"rebuilt_dependencies"`
+	RestoredDependencies []string `json:"restored_dependencies"`
+	BuildDir             string   `json:"build_dir"`
+	DurationSeconds      int      `json:"duration_seconds"`
+	FailureReason        string   `json:"failure_reason,omitempty"`
+	Error                string   `json:"error,omitempty"`
+}
+
+func (projectResult ProjectResult) summary() string {
+	switch {
+	case projectResult.Err != nil:
+		return fmt.Sprintf("failed (%s)", failureReason(projectResult.Err))
+	case projectResult.result.cacheHit:
+		return "cache hit"
+	case projectResult.result.rebuiltAll:
+		return "all dependencies built"
+	case len(projectResult.result.rebuiltDependencies) > 0:
+		return fmt.Sprintf("%d dependencies built", len(projectResult.result.rebuiltDependencies))
+	case len(projectResult.result.restoredDependencies) > 0:
+		return fmt.Sprintf("%d dependencies restored", len(projectResult.result.restoredDependencies))
+	default:
+		return "done"
+	}
+}
+
+// RunProjects runs the Runners of the projects, at most parallelism of them at a time, and prints a summary of their results.
+// If the projects run in parallel, the lines of the Carthage output are prefixed with the project dir,
+// and the projects check out their dependencies one at a time.
+// Instead of the outputs of a single project, it exports the aggregated outputs and the path of the JSON summary
+// written into the deploy dir (if set).
+// The returned error lists the failed projects, the results are in the order of the runners.
+func RunProjects(runners []Runner, parallelism int, deployDir string, outputExporter OutputExporter) ([]ProjectResult, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var outputLock, checkoutLock sync.Mutex
+	startTime := time.Now()
+	results := make([]ProjectResult, len(runners))
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, runner := range runners {
+		if parallelism > 1 {
+			runner.outputPrefix = fmt.Sprintf("[%s] ", runner.project.projectDir)
+			runner.outputLock = &outputLock
+			runner.checkoutLock = &checkoutLock
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, runner Runner) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			fmt.Println()
+			log.Infof("Running Carthage in %s", runner.project.projectDir)
+
+			startTime := time.Now()
+			result, err := runner.run()
+			results[i] = ProjectResult{
+				ProjectDir:          runner.project.projectDir,
+				Duration:            time.Since(startTime),
+				Err:                 err,
+				result:              result,
+				rebuiltDependencies: runner.rebuiltDependencies(result),
+				buildDir:            runner.absoluteBuildDir(),
+			}
+		}(i, runner)
+	}
+	wg.Wait()
+
+	printProjectSummary(results)
+	exportProjectsOutputs(results, time.Since(startTime), deployDir, outputExporter)
+
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.ProjectDir, result.Err))
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d of %d projects failed:\n%s", len(failed), len(results), strings.Join(failed, "\n"))
+	}
+	return results, nil
+}
+
+func printProjectSummary(results []ProjectResult) {
+	fmt.Println()
+	log.Infof("Summary:")
+	for _, result := range results {
+		line := fmt.Sprintf("- %s: %s in %s", result.ProjectDir, result.summary(), result.Duration.Round(time.Second))
+		if result.Err != nil {
+			log.Errorf("%s", line)
+		} else {
+			log.Printf("%s", line)
+		}
+	}
+}
+
+// exportProjectsOutputs exports the outputs aggregated over the projects: the cache is hit if every project hit it,
+// and the failure reason is the one of the first failed project.
+func exportProjectsOutputs(results []ProjectResult, duration time.Duration, deployDir string, outputExporter OutputExporter) {
+	if outputExporter == nil {
+		return
+	}
+
+	cacheHit := len(results) > 0
+	var reason FailureReason
+	for _, result := range results {
+		cacheHit = cacheHit && result.result.cacheHit
+		if reason == "" {
+			reason = failureReason(result.Err)
+		}
+	}
+
+	outputs := []stepOutput{
+		{CacheHitOutputKey, strconv.FormatBool(cacheHit)},
+		{DurationSecondsOutputKey, strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		{FailureReasonOutputKey, string(reason)},
+	}
+
+	if deployDir != "" {
+		pth, err := writeProjectsSummary(results, deployDir)
+		if err != nil {
+			log.Warnf("Failed to export the projects summary, error: %s", err)
+		} else {
+			outputs = append(outputs, stepOutput{ProjectsSummaryPathOutputKey, pth})
+		}
+	}
+
+	for _, output := range outputs {
+		if err := outputExporter.ExportOutput(output.key, output.value); err != nil {
+			log.Warnf("Failed to export output (%s), error: %s", output.key, err)
+		}
+	}
+}
+
+// writeProjectsSummary writes the results of the projects as JSON into the deploy dir, and returns the path of the file.
+func writeProjectsSummary(results []ProjectResult, deployDir string) (string, error) {
+	summaries := []projectSummary{}
+	for _, result := range results {
+		summary := projectSummary{
+			ProjectDir:           result.ProjectDir,
+			CacheHit:             result.result.cacheHit,
+			RebuiltDependencies:  nonNil(result.rebuiltDependencies),
+			RestoredDependencies: nonNil(result.result.restoredDependencies),
+			BuildDir:             result.buildDir,
+			DurationSeconds:      int(result.Duration.Round(time.Second).Seconds()),
+			FailureReason:        string(failureReason(result.Err)),
+		}
+		if result.Err != nil {
+			summary.Error = result.Err.Error()
+		}
+		summaries = append(summaries, summary)
+	}
+
+	content, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	pth := filepath.Join(deployDir, projectsSummaryFileName)
+	if err := ioutil.WriteFile(pth, content, 0666); err != nil {
+		return "", fmt.Errorf("failed to write %s, error: %s", pth, err)
+	}
+	return pth, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// performCheckout runs the Carthage command with the options, holding the checkout lock if the projects run in parallel,
+// as their Carthage commands share the CarthageKit dependency repositories.
+func (runner Runner) performCheckout(options, dependencies []string) error {
+	if runner.checkoutLock != nil {
+		runner.checkoutLock.Lock()
+		defer runner.checkoutLock.Unlock()
+	}
+
+	return runner.perform(options, dependencies)
+}
+
+// checkoutAndBuild checks out the dependencies holding the checkout lock, then builds them with `carthage build`,
+// so the projects run in parallel check out one at a time, but build at the same time.
+func (runner Runner) checkoutAndBuild(dependencies []string) error {
+	if err := runner.performCheckout([]string{noBuildArg}, dependencies); err != nil {
+		return err
+	}
+
+	buildRunner := runner
+	buildRunner.carthageCommand = buildCommand
+	buildRunner.args = runner.parallelBuild.BuildArgs
+	return buildRunner.perform(nil, dependencies)
+}
+
+// SynchronizedFileCache commits the wrapped FileCache holding the lock, so the caches of projects run in parallel
+// can share the include paths env of the Cache:Push step.
+type SynchronizedFileCache struct {
+	filecache FileCache
+	lock      sync.Locker
+}
+
+// NewSynchronizedFileCache ...
+func NewSynchronizedFileCache(filecache FileCache, lock sync.Locker) SynchronizedFileCache {
+	return SynchronizedFileCache{filecache: filecache, lock: lock}
+}
+
+// IncludePath ...
+func (cache SynchronizedFileCache) IncludePath(paths ...string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.filecache.IncludePath(paths...)
+}
+
+// Location returns the location of the wrapped FileCache, if it is a RemoteFileCache.
+func (cache SynchronizedFileCache) Location() string {
+	if remote, ok := cache.filecache.(RemoteFileCache); ok {
+		return remote.Location()
+	}
+	return ""
+}
+
+// Commit ...
+func (cache SynchronizedFileCache) Commit() error {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return cache.filecache.Commit()
+}
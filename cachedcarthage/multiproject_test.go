@@ -0,0 +1,146 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenProjects_WhenRunProjectsCalled_ThenExpectResultsInRunnerOrder(t *testing.T) {
+	testScenarios := []struct {
+		parallelism int
+	}{
+		{parallelism: 1},
+		{parallelism: 3},
+	}
+
+	for _, testScenario := range testScenarios {
+		// Given
+		runners := []Runner{
+			{carthageCommand: "version", commandBuilder: givenStubbedCommandBuilder(), project: Project{"/app1"}},
+			{carthageCommand: "version", commandBuilder: givenStubbedCommandBuilderReturnFailingCommand(), project: Project{"/app2"}},
+			{carthageCommand: "version", commandBuilder: givenStubbedCommandBuilder(), project: Project{"/app3"}},
+		}
+
+		// When
+		results, err := RunProjects(runners, testScenario.parallelism, "", nil)
+
+		// Then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "1 of 3 projects failed:\n/app2: ")
+		require.Len(t, results, 3)
+		assert.Equal(t, "/app1", results[0].ProjectDir)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, "/app2", results[1].ProjectDir)
+		assert.Error(t, results[1].Err)
+		assert.Equal(t, "failed (unknown)", results[1].summary())
+		assert.Equal(t, "/app3", results[2].ProjectDir)
+		assert.NoError(t, results[2].Err)
+	}
+}
+
+func Test_GivenBootstrapCacheHit_WhenRunProjectsCalled_ThenExpectNoErrorAndCacheHitSummary(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(true).
+		GivenCommitSucceeds()
+	runners := []Runner{
+		{carthageCommand: "bootstrap", cache: mockCarthageCache, commandBuilder: givenStubbedCommandBuilder(), project: Project{"/app1"}},
+	}
+
+	// When
+	results, err := RunProjects(runners, 1, "", nil)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "cache hit", results[0].summary())
+}
+
+func Test_GivenProjectsAndDeployDir_WhenRunProjectsCalled_ThenExpectAggregatedOutputsAndSummary(t *testing.T) {
+	// Given
+	deployDir := t.TempDir()
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(true).
+		GivenCommitSucceeds()
+	runners := []Runner{
+		{carthageCommand: "bootstrap", cache: mockCarthageCache, commandBuilder: givenStubbedCommandBuilder(), project: Project{"/app1"}},
+		{carthageCommand: "version", commandBuilder: givenStubbedCommandBuilderReturnFailingCommand(), project: Project{"/app2"}},
+	}
+
+	// When
+	_, err := RunProjects(runners, 1, deployDir, mockOutputExporter)
+
+	// Then
+	require.Error(t, err)
+	summaryPth := filepath.Join(deployDir, "carthage-projects.json")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", CacheHitOutputKey, "false")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", FailureReasonOutputKey, string(FailureReasonUnknown))
+	mockOutputExporter.AssertCalled(t, "ExportOutput", ProjectsSummaryPathOutputKey, summaryPth)
+
+	content, err := ioutil.ReadFile(summaryPth)
+	require.NoError(t, err)
+	var summaries []projectSummary
+	require.NoError(t, json.Unmarshal(content, &summaries))
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "/app1", summaries[0].ProjectDir)
+	assert.True(t, summaries[0].CacheHit)
+	assert.Empty(t, summaries[0].Error)
+	assert.Equal(t, "/app2", summaries[1].ProjectDir)
+	assert.False(t, summaries[1].CacheHit)
+	assert.Equal(t, string(FailureReasonUnknown), summaries[1].FailureReason)
+	assert.NotEmpty(t, summaries[1].Error)
+}
+
+func Test_GivenProjectsRunInParallel_WhenRunProjectsCalled_ThenExpectCheckoutsSerialized(t *testing.T) {
+	// Given
+	fakeCarthage := givenFakeCarthage(t, "")
+	runners := []Runner{
+		{carthageCommand: "update", commandBuilder: fakeCarthage, project: Project{t.TempDir()}},
+		{carthageCommand: "update", commandBuilder: fakeCarthage, project: Project{t.TempDir()}},
+	}
+
+	// When
+	_, err := RunProjects(runners, 2, "", nil)
+
+	// Then
+	require.NoError(t, err)
+	var checkoutCalls []string
+	buildCalls := 0
+	for _, call := range fakeCarthage.calls(t) {
+		switch {
+		case strings.HasSuffix(call, "update --no-build"):
+			checkoutCalls = append(checkoutCalls, call)
+		case strings.HasPrefix(call, "start build"):
+			buildCalls++
+		}
+	}
+	assert.Equal(t, []string{
+		"start update --no-build", "end update --no-build",
+		"start update --no-build", "end update --no-build",
+	}, checkoutCalls)
+	assert.Equal(t, 2, buildCalls)
+}
+
+func Test_GivenSynchronizedFileCache_WhenCommitCalled_ThenExpectWrappedCacheCommitted(t *testing.T) {
+	// Given
+	mockFileCache := givenMockFileCache().
+		GivenIncludeSucceeds().
+		GivenCommitSucceeds()
+	cache := NewSynchronizedFileCache(mockFileCache, &sync.Mutex{})
+
+	// When
+	cache.IncludePath("/app1/Carthage")
+	err := cache.Commit()
+
+	// Then
+	assert.NoError(t, err)
+	mockFileCache.AssertCalled(t, "IncludePath", []string{"/app1/Carthage"})
+	mockFileCache.AssertCalled(t, "Commit")
+}
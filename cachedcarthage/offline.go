@@ -0,0 +1,74 @@
+package cachedcarthage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// offlineProxy is the discard port of the loopback interface, connecting to it fails right away.
+const offlineProxy = "http://127.0.0.1:9"
+
+// offlineEnvs make the git and curl network access of the Carthage commands fail: HTTP(S) goes through an unreachable proxy,
+// and git accepts only local repositories.
+var offlineEnvs = []string{
+	"http_proxy=" + offlineProxy,
+	"https_proxy=" + offlineProxy,
+	"all_proxy=" + offlineProxy,
+	"HTTP_PROXY=" + offlineProxy,
+	"HTTPS_PROXY=" + offlineProxy,
+	"ALL_PROXY=" + offlineProxy,
+	"no_proxy=",
+	"NO_PROXY=",
+	"GIT_ALLOW_PROTOCOL=file",
+	"GIT_SSH_COMMAND=false",
+	"GIT_TERMINAL_PROMPT=0",
+}
+
+// Carthage prints `*** Cloning Alamofire`, `*** Fetching Alamofire`, `*** Downloading Alamofire binary at "5.4.1"`
+// and `*** Downloading binary-only framework FirebaseAnalytics at "https://..."` before accessing the network.
+var networkAccessPattern = regexp.MustCompile(`(?m)^\*\*\* (?:Cloning|Fetching|Downloading(?: binary-only framework)?) (\S+)`)
+
+// parseNetworkDependencies returns the dependencies the Carthage command accessed the network for, in the order of the output.
+func parseNetworkDependencies(output string) []string {
+	var dependencies []string
+	for _, match := range networkAccessPattern.FindAllStringSubmatch(ansiEscapePattern.ReplaceAllString(output, ""), -1) {
+		name := strings.TrimSuffix(strings.TrimSuffix(match[1], ".framework"), ".xcframework")
+		if !contains(dependencies, name) {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies
+}
+
+// reportNetworkDependencies extends the error of a Carthage command failed in offline mode with the dependencies which needed network access.
+func reportNetworkDependencies(runnerErr *RunnerError) {
+	dependencies := parseNetworkDependencies(runnerErr.CombinedOutput)
+	if len(dependencies) == 0 {
+		return
+	}
+
+	log.Errorf("Offline mode, network access was needed for: %s", strings.Join(dependencies, ", "))
+	runnerErr.Reason = FailureReasonNetworkRequired
+	runnerErr.Err = fmt.Errorf("network access needed in offline mode for: %s, error: %s", strings.Join(dependencies, ", "), runnerErr.Err)
+}
+
+// offlineCacheMissError returns the error of a cache miss in offline mode, listing the dependencies to build (every dependency if empty).
+func (runner Runner) offlineCacheMissError(dependencies []string) error {
+	if len(dependencies) == 0 {
+		names, err := runner.project.resolvedDependencyNames()
+		if err != nil {
+			log.Warnf("Failed to list the dependencies, error: %s", err)
+		}
+		dependencies = names
+	}
+
+	message := "cache not available in offline mode"
+	if len(dependencies) > 0 {
+		message += fmt.Sprintf(", network access would be needed for: %s", strings.Join(dependencies, ", "))
+	}
+	return &RunnerError{Err: errors.New(message), Reason: FailureReasonNetworkRequired}
+}
@@ -0,0 +1,79 @@
+package cachedcarthage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenCarthageOutput_WhenParseNetworkDependenciesCalled_ThenExpectDependencies(t *testing.T) {
+	// Given
+	output := `*** Cloning Alamofire
+*** Fetching Moya
+*** Fetching Alamofire
+*** Downloading Moya.framework binary at "15.0.0"
+*** Downloading binary-only framework FirebaseAnalytics at "https://dl.google.com/FirebaseAnalyticsBinary.json"
+*** Checking out Alamofire at "5.4.1"
+*** Building scheme "Alamofire iOS" in Alamofire.xcworkspace
+`
+
+	// When
+	dependencies := parseNetworkDependencies(output)
+
+	// Then
+	assert.Equal(t, []string{"Alamofire", "Moya", "FirebaseAnalytics"}, dependencies)
+}
+
+func Test_GivenOfflineBootstrapAndCacheNotAvailable_WhenRunCalled_ThenExpectErrorWithoutRunningCarthage(t *testing.T) {
+	// Given
+	projectDir := givenTempDir(t)
+	givenFile(t, filepath.Join(projectDir, resolvedFileName), `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "15.0.0"
+`)
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           givenMockCarthageCache().GivenIsAvailableSucceeds(false).GivenOutdatedDependenciesSucceeds(nil),
+		commandBuilder:  mockCommandBuilder,
+		offline:         true,
+		project:         NewProject(projectDir),
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	require.EqualError(t, err, "cache not available in offline mode, network access would be needed for: Alamofire, Moya")
+	var runnerErr *RunnerError
+	require.True(t, errors.As(err, &runnerErr))
+	assert.Equal(t, FailureReasonNetworkRequired, runnerErr.Reason)
+	mockCommandBuilder.AssertNotCalled(t, "Command")
+}
+
+func Test_GivenOfflineCommandAccessesNetwork_WhenRunCalled_ThenExpectNetworkDependenciesReported(t *testing.T) {
+	// Given
+	mockCommandBuilder := givenStubbedCommandBuilderReturnsCommands([]*command.Model{
+		command.New("bash", "-c", "echo '*** Fetching Alamofire' && echo 'fatal: transport https not allowed' 1>&2 && false"),
+	}).GivenAddEnvsSucceeds()
+	runner := Runner{
+		carthageCommand: "update",
+		commandBuilder:  mockCommandBuilder,
+		retryPolicy:     NewRetryPolicy(2, 0, 1),
+		offline:         true,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	require.EqualError(t, err, "Carthage command failed, error: network access needed in offline mode for: Alamofire, error: exit status 1")
+	var runnerErr *RunnerError
+	require.True(t, errors.As(err, &runnerErr))
+	assert.Equal(t, FailureReasonNetworkRequired, runnerErr.Reason)
+	mockCommandBuilder.AssertCalled(t, "AddEnvs", offlineEnvs)
+	mockCommandBuilder.AssertNumberOfCalls(t, "Command", 1)
+}
@@ -0,0 +1,125 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	outdatedCommand = "outdated"
+
+	outdatedReportFileName   = "carthage-outdated.json"
+	outdatedMarkdownFileName = "carthage-outdated.md"
+)
+
+var (
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	// Carthage prints `Alamofire "5.4.0" -> "5.4.1" (Latest: "5.6.0")` for every outdated dependency,
+	// and `warning: Alamofire is out of date (5.4.0 -> 5.4.1) (Latest: 5.6.0)` with the `--xcode-warnings` option.
+	// Carthage versions before 0.30 do not print the latest version.
+	outdatedDependencyPattern   = regexp.MustCompile(`^(\S+) "([^"]*)" -> "([^"]*)"(?: \(Latest: "([^"]*)"\))?$`)
+	outdatedXcodeWarningPattern = regexp.MustCompile(`^warning: (\S+) is out of date \(([^)]*) -> ([^)]*)\)(?: \(Latest: ([^)]*)\))?$`)
+	outdatedPatterns            = []*regexp.Regexp{outdatedDependencyPattern, outdatedXcodeWarningPattern}
+)
+
+// OutdatedReport lists the outdated dependencies reported by the `carthage outdated` command.
+type OutdatedReport struct {
+	Dependencies []OutdatedDependency `json:"dependencies"`
+}
+
+// OutdatedDependency is a dependency with a newer version than the one pinned in the Cartfile.resolved.
+type OutdatedDependency struct {
+	Dependency string `json:"dependency"`
+	Current    string `json:"current"`
+	// LatestAllowed is the latest version satisfying the Cartfile requirement, the version `carthage update` would pin.
+	LatestAllowed string `json:"latest_allowed"`
+	// LatestAvailable is empty if the Carthage version does not report it.
+	LatestAvailable string `json:"latest_available,omitempty"`
+}
+
+// parseOutdatedReport parses the outdated dependencies from the output of the `carthage outdated` command.
+func parseOutdatedReport(output string) OutdatedReport {
+	report := OutdatedReport{Dependencies: []OutdatedDependency{}}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(ansiEscapePattern.ReplaceAllString(line, ""))
+		for _, pattern := range outdatedPatterns {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				report.Dependencies = append(report.Dependencies, OutdatedDependency{
+					Dependency:      match[1],
+					Current:         match[2],
+					LatestAllowed:   match[3],
+					LatestAvailable: match[4],
+				})
+				break
+			}
+		}
+	}
+	return report
+}
+
+// Markdown returns the report as a Markdown table, ready to be posted as a pull request comment.
+func (report OutdatedReport) Markdown() string {
+	if len(report.Dependencies) == 0 {
+		return "All Carthage dependencies are up to date.\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%d outdated Carthage dependencies**\n\n", len(report.Dependencies))
+	b.WriteString("| Dependency | Current | Latest allowed by Cartfile | Latest available |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, dependency := range report.Dependencies {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", dependency.Dependency, dependency.Current, dependency.LatestAllowed, orDash(dependency.LatestAvailable))
+	}
+	return b.String()
+}
+
+// reportOutdated runs the `carthage outdated` command and parses the outdated dependencies from its output.
+func (runner Runner) reportOutdated() (runResult, error) {
+	output, err := runner.runCommand(nil, nil)
+	if err != nil {
+		return runResult{}, runner.commandFailed(err)
+	}
+
+	report := parseOutdatedReport(output)
+
+	fmt.Println()
+	if len(report.Dependencies) == 0 {
+		log.Donef("All dependencies are up to date")
+	} else {
+		log.Warnf("%d outdated dependencies:", len(report.Dependencies))
+		for _, dependency := range report.Dependencies {
+			line := fmt.Sprintf("- %s: %s -> %s", dependency.Dependency, dependency.Current, dependency.LatestAllowed)
+			if dependency.LatestAvailable != "" {
+				line += fmt.Sprintf(" (latest: %s)", dependency.LatestAvailable)
+			}
+			log.Printf("%s", line)
+		}
+	}
+
+	return runResult{outdatedReport: &report}, nil
+}
+
+// exportOutdatedReport writes the report as JSON and Markdown into the deploy dir, and returns the paths of the files.
+func (runner Runner) exportOutdatedReport(report OutdatedReport) (string, string, error) {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+
+	reportPth := filepath.Join(runner.deployDir, outdatedReportFileName)
+	if err := ioutil.WriteFile(reportPth, content, 0666); err != nil {
+		return "", "", fmt.Errorf("failed to write %s, error: %s", reportPth, err)
+	}
+	markdownPth := filepath.Join(runner.deployDir, outdatedMarkdownFileName)
+	if err := ioutil.WriteFile(markdownPth, []byte(report.Markdown()), 0666); err != nil {
+		return "", "", fmt.Errorf("failed to write %s, error: %s", markdownPth, err)
+	}
+
+	return reportPth, markdownPth, nil
+}
@@ -0,0 +1,116 @@
+package cachedcarthage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outdatedOutput = `*** Fetching Moya
+*** Fetching Alamofire
+The following dependencies are outdated:
+` + "\x1b[33mAlamofire \"5.4.0\" -> \"5.4.1\" (Latest: \"5.6.0\")\x1b[0m" + `
+Moya "14.0.0" -> "14.0.0" (Latest: "15.0.0")
+Legend — <color> • Will be updated when you run ` + "`carthage update`." + `
+`
+
+func Test_GivenCarthageOutdatedOutput_WhenParseOutdatedReportCalled_ThenExpectOutdatedDependencies(t *testing.T) {
+	testScenarios := []struct {
+		name     string
+		output   string
+		expected []OutdatedDependency
+	}{
+		{
+			name:   "colored output",
+			output: outdatedOutput,
+			expected: []OutdatedDependency{
+				{Dependency: "Alamofire", Current: "5.4.0", LatestAllowed: "5.4.1", LatestAvailable: "5.6.0"},
+				{Dependency: "Moya", Current: "14.0.0", LatestAllowed: "14.0.0", LatestAvailable: "15.0.0"},
+			},
+		},
+		{
+			name:   "xcode warnings",
+			output: "warning: Alamofire is out of date (5.4.0 -> 5.4.1) (Latest: 5.6.0)\n",
+			expected: []OutdatedDependency{
+				{Dependency: "Alamofire", Current: "5.4.0", LatestAllowed: "5.4.1", LatestAvailable: "5.6.0"},
+			},
+		},
+		{
+			name:   "without latest version",
+			output: "The following dependencies are outdated:\nAlamofire \"5.4.0\" -> \"5.4.1\"\n",
+			expected: []OutdatedDependency{
+				{Dependency: "Alamofire", Current: "5.4.0", LatestAllowed: "5.4.1"},
+			},
+		},
+		{
+			name:     "up to date",
+			output:   "*** Fetching Alamofire\nAll dependencies are up to date.\n",
+			expected: []OutdatedDependency{},
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// When
+			report := parseOutdatedReport(testScenario.output)
+
+			// Then
+			assert.Equal(t, testScenario.expected, report.Dependencies)
+		})
+	}
+}
+
+func Test_GivenOutdatedReport_WhenMarkdownCalled_ThenExpectTable(t *testing.T) {
+	// Given
+	report := OutdatedReport{Dependencies: []OutdatedDependency{
+		{Dependency: "Alamofire", Current: "5.4.0", LatestAllowed: "5.4.1", LatestAvailable: "5.6.0"},
+		{Dependency: "Moya", Current: "14.0.0", LatestAllowed: "14.0.0"},
+	}}
+
+	// When
+	markdown := report.Markdown()
+
+	// Then
+	assert.Equal(t, `**2 outdated Carthage dependencies**
+
+| Dependency | Current | Latest allowed by Cartfile | Latest available |
+| --- | --- | --- | --- |
+| Alamofire | 5.4.0 | 5.4.1 | 5.6.0 |
+| Moya | 14.0.0 | 14.0.0 | - |
+`, markdown)
+}
+
+func Test_GivenOutdatedCommand_WhenRunCalled_ThenExpectReportExported(t *testing.T) {
+	// Given
+	deployDir := givenTempDir(t)
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "outdated",
+		commandBuilder:  givenStubbedCommandBuilderReturnsCommands([]*command.Model{command.New("printf", "%s", outdatedOutput)}),
+		project:         NewProject("/base/dir"),
+		deployDir:       deployDir,
+		outputExporter:  mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	require.NoError(t, err)
+	reportPth := filepath.Join(deployDir, outdatedReportFileName)
+	markdownPth := filepath.Join(deployDir, outdatedMarkdownFileName)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", OutdatedCountOutputKey, "2")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", OutdatedReportPathOutputKey, reportPth)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", OutdatedMarkdownPathOutputKey, markdownPth)
+
+	content, err := ioutil.ReadFile(reportPth)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"latest_allowed": "5.4.1"`)
+	markdown, err := ioutil.ReadFile(markdownPth)
+	require.NoError(t, err)
+	assert.Contains(t, string(markdown), "| Alamofire | 5.4.0 | 5.4.1 | 5.6.0 |")
+}
@@ -0,0 +1,49 @@
+package cachedcarthage
+
+import (
+	"github.com/bitrise-io/go-steputils/tools"
+)
+
+// Step outputs exported by the Runner.
+const (
+	CacheHitOutputKey            = "CARTHAGE_CACHE_HIT"
+	RebuiltDependenciesOutputKey = "CARTHAGE_REBUILT_DEPENDENCIES"
+	BuildDirOutputKey            = "CARTHAGE_BUILD_DIR"
+	DurationSecondsOutputKey     = "CARTHAGE_DURATION_SECONDS"
+	FailureReasonOutputKey       = "CARTHAGE_FAILURE_REASON"
+
+	// Exported only by the outdated command.
+	OutdatedCountOutputKey        = "CARTHAGE_OUTDATED_COUNT"
+	OutdatedReportPathOutputKey   = "CARTHAGE_OUTDATED_REPORT_PATH"
+	OutdatedMarkdownPathOutputKey = "CARTHAGE_OUTDATED_MARKDOWN_PATH"
+
+	// Exported only by RunProjects, if the deploy dir is set.
+	ProjectsSummaryPathOutputKey = "CARTHAGE_PROJECTS_SUMMARY_PATH"
+
+	// Exported only by the update command, if the DriftGuard is enabled.
+	ResolvedChangedDependenciesOutputKey = "CARTHAGE_RESOLVED_CHANGED_DEPENDENCIES"
+	ResolvedDiffPathOutputKey            = "CARTHAGE_RESOLVED_DIFF_PATH"
+)
+
+type stepOutput struct {
+	key   string
+	value string
+}
+
+// OutputExporter ...
+type OutputExporter interface {
+	ExportOutput(key, value string) error
+}
+
+// EnvmanOutputExporter exports the step outputs with envman, so they are available for the subsequent steps.
+type EnvmanOutputExporter struct{}
+
+// NewEnvmanOutputExporter ...
+func NewEnvmanOutputExporter() EnvmanOutputExporter {
+	return EnvmanOutputExporter{}
+}
+
+// ExportOutput ...
+func (exporter EnvmanOutputExporter) ExportOutput(key, value string) error {
+	return tools.ExportEnvironmentWithEnvman(key, value)
+}
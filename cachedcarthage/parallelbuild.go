@@ -0,0 +1,292 @@
+package cachedcarthage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// ParallelBuild tells if the step builds the dependencies itself, with a `carthage build <dependency>` per dependency,
+// instead of the Carthage command building them one after the other.
+type ParallelBuild struct {
+	// Workers is the number of dependencies built at a time, the step builds the dependencies only if it is greater than 1.
+	Workers uint
+	// KeepGoing lets the builds of the dependencies not depending on a failed one go on, instead of stopping at the first failure.
+	KeepGoing bool
+	// BuildArgs are the options of the Carthage command accepted by `carthage build`.
+	BuildArgs []string
+}
+
+// NewParallelBuild ...
+func NewParallelBuild(workers uint, keepGoing bool, buildArgs []string) ParallelBuild {
+	return ParallelBuild{
+		Workers:   workers,
+		KeepGoing: keepGoing,
+		BuildArgs: buildArgs,
+	}
+}
+
+// dependencyBuildResult is the result of the build of a dependency.
+type dependencyBuildResult struct {
+	name string
+	err  error
+}
+
+// isParallelBuild tells if the dependencies are checked out by the Carthage command, and built one by one by the step.
+func (runner Runner) isParallelBuild() bool {
+	return (runner.carthageCommand == bootstrapCommand || runner.carthageCommand == updateCommand) &&
+		runner.parallelBuild.Workers > 1 && runner.buildsDependencies()
+}
+
+// build runs the Carthage command to provide the dependencies (every dependency if empty), with the options appended.
+// If the dependencies are built in parallel, the Carthage command only checks them out (unless the checkout was already run),
+// and the dependencies are built one by one.
+// If the projects run in parallel, the checkout is run holding the checkout lock (see checkoutAndBuild).
+func (runner Runner) build(options, dependencies []string) error {
+	if !runner.isParallelBuild() {
+		switch {
+		case runner.checkoutLock == nil || runner.isSplit() || !runner.checksOut():
+			return runner.perform(options, dependencies)
+		case !runner.buildsDependencies():
+			return runner.performCheckout(options, dependencies)
+		default:
+			return runner.checkoutAndBuild(dependencies)
+		}
+	}
+
+	if !runner.isSplit() {
+		if err := runner.performCheckout([]string{noBuildArg}, dependencies); err != nil {
+			return err
+		}
+	}
+
+	graph, err := runner.project.dependencyGraph()
+	if err != nil {
+		return fmt.Errorf("failed to determine the dependency graph, error: %s", err)
+	}
+	if len(dependencies) == 0 {
+		for name := range graph {
+			dependencies = append(dependencies, name)
+		}
+		sort.Strings(dependencies)
+	}
+
+	return runner.buildInParallel(graph, dependencies)
+}
+
+// buildInParallel builds the dependencies, at most Workers of them at a time, every dependency after the ones it depends on.
+// Dependencies of the graph which are not built are expected to be built already.
+func (runner Runner) buildInParallel(graph dependencyGraph, dependencies []string) error {
+	fmt.Println()
+	log.Infof("Building %d dependencies, %d at a time", len(dependencies), runner.parallelBuild.Workers)
+
+	toBuild := map[string]bool{}
+	for _, name := range dependencies {
+		toBuild[name] = true
+	}
+
+	var outputLock sync.Locker = &sync.Mutex{}
+	if runner.outputLock != nil {
+		outputLock = runner.outputLock
+	}
+	results := make(chan dependencyBuildResult)
+	started, built, failed, skipped := map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}
+	var failures []dependencyBuildResult
+	running := 0
+
+	for {
+		stopped := len(failures) > 0 && !runner.parallelBuild.KeepGoing
+		for _, name := range dependencies {
+			if stopped || running == int(runner.parallelBuild.Workers) {
+				break
+			}
+			if started[name] {
+				continue
+			}
+
+			ready := true
+			for _, dependency := range graph[name] {
+				if !toBuild[dependency] || built[dependency] {
+					continue
+				}
+				ready = false
+				if failed[dependency] || skipped[dependency] {
+					// Dependents of a failed build are not built either
+					started[name], skipped[name] = true, true
+					withLock(outputLock, func() {
+						log.Warnf("%s[%s] Skipped, as %s failed", runner.outputPrefix, name, dependency)
+					})
+				}
+				break
+			}
+			if !ready {
+				continue
+			}
+
+			started[name] = true
+			running++
+			go func(name string) {
+				results <- dependencyBuildResult{name: name, err: runner.buildDependency(name, outputLock)}
+			}(name)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		withLock(outputLock, func() {
+			if result.err != nil {
+				log.Errorf("%s[%s] Build failed", runner.outputPrefix, result.name)
+			} else {
+				log.Donef("%s[%s] Built", runner.outputPrefix, result.name)
+			}
+		})
+		if result.err != nil {
+			failed[result.name] = true
+			failures = append(failures, result)
+		} else {
+			built[result.name] = true
+		}
+	}
+
+	var notBuilt []string
+	for _, name := range dependencies {
+		if !built[name] && !failed[name] {
+			notBuilt = append(notBuilt, name)
+		}
+	}
+
+	if len(failures) == 0 {
+		if len(notBuilt) > 0 {
+			return fmt.Errorf("dependency cycle among: %s", strings.Join(notBuilt, ", "))
+		}
+		return nil
+	}
+
+	return dependencyBuildError(failures, notBuilt)
+}
+
+// dependencyBuildError returns the error of the first failed build, extended with the rest of the failed and not built dependencies.
+func dependencyBuildError(failures []dependencyBuildResult, notBuilt []string) error {
+	message := fmt.Sprintf("failed to build %s, error: %s", failures[0].name, failures[0].err)
+	if len(failures) > 1 {
+		var names []string
+		for _, failure := range failures[1:] {
+			names = append(names, failure.name)
+		}
+		message += fmt.Sprintf(", also failed: %s", strings.Join(names, ", "))
+	}
+	if len(notBuilt) > 0 {
+		message += fmt.Sprintf(", not built: %s", strings.Join(notBuilt, ", "))
+	}
+
+	var runnerErr *RunnerError
+	if errors.As(failures[0].err, &runnerErr) {
+		runnerErr.Err = errors.New(message)
+		return runnerErr
+	}
+	return errors.New(message)
+}
+
+// newBuildCommand builds the `carthage build` command of the dependency.
+// As `carthage build <dependency>` builds the dependencies of the dependency too, the command always gets the `--cache-builds` option,
+// so the dependencies already built by the step are not rebuilt by every dependent.
+func (runner Runner) newBuildCommand(name string) *command.Model {
+	builder := runner.commandBuilder.
+		AddGitHubToken(runner.githubAccessToken).
+		AddXCConfigFile(runner.xcconfigPath).
+		Append(buildCommand).
+		Append(runner.parallelBuild.BuildArgs...)
+	if !contains(runner.parallelBuild.BuildArgs, cacheBuildsArg) {
+		builder = builder.Append(cacheBuildsArg)
+	}
+	builder = builder.Append(name)
+	if runner.offline {
+		builder = builder.AddEnvs(offlineEnvs...)
+	}
+	return builder.Command()
+}
+
+// buildDependency builds the dependency, every line of its output is prefixed with the name of the dependency.
+func (runner Runner) buildDependency(name string, outputLock sync.Locker) error {
+	prefix := fmt.Sprintf("%s[%s] ", runner.outputPrefix, name)
+
+	outputTail, stderrTail := newTailBuffer(commandOutputTailSize), newTailBuffer(commandOutputTailSize)
+	output := &lockedWriter{writer: outputTail}
+	stdout := &prefixWriter{prefix: prefix, writer: os.Stdout, lock: outputLock}
+	stderr := &prefixWriter{prefix: prefix, writer: os.Stderr, lock: outputLock}
+
+	cmd := runner.newBuildCommand(name)
+	cmd.SetStdout(io.MultiWriter(stdout, output))
+	cmd.SetStderr(io.MultiWriter(stderr, output, stderrTail))
+
+	withLock(outputLock, func() {
+		log.Donef("%s$ %s", prefix, cmd.PrintableCommandArgs())
+	})
+
+	err := cmd.Run()
+	stdout.flush()
+	stderr.flush()
+
+	if err == nil {
+		return nil
+	}
+	return newRunnerError(stderrTail.String(), outputTail.String(), err)
+}
+
+// prefixWriter writes every line with the prefix, holding the lock, so the lines of the commands run in parallel are not mixed.
+type prefixWriter struct {
+	prefix string
+	writer io.Writer
+	lock   sync.Locker
+	// pending is the last, not yet terminated line.
+	pending []byte
+}
+
+// Write ...
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.pending[:i+1]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[i+1:]
+	}
+}
+
+// flush writes the last line, even if it is not terminated.
+func (w *prefixWriter) flush() {
+	if len(w.pending) > 0 {
+		_ = w.writeLine(append(w.pending, '\n'))
+		w.pending = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.writer.Write(append([]byte(w.prefix), line...))
+	return err
+}
+
+func withLock(lock sync.Locker, f func()) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	f()
+}
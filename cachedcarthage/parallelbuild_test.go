@@ -0,0 +1,270 @@
+package cachedcarthage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenBootstrapWithParallelBuild_WhenBuildCalled_ThenExpectCheckoutAndBuildsInDependencyOrder(t *testing.T) {
+	// Given
+	project := givenProjectWithCheckouts(t, `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "15.0.0"
+github "ReactiveX/RxSwift" "6.2.0"
+`, map[string]givenCheckout{
+		"Moya": {cartfile: `github "Alamofire/Alamofire" ~> 5.4
+github "ReactiveX/RxSwift" ~> 6.0
+`},
+	})
+	fakeCarthage := givenFakeCarthage(t, "")
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		args:            []string{"--platform", "ios", "--use-ssh"},
+		commandBuilder:  fakeCarthage,
+		parallelBuild:   NewParallelBuild(2, false, []string{"--platform", "ios"}),
+		project:         project,
+	}
+
+	// When
+	err := runner.build(nil, nil)
+
+	// Then
+	require.NoError(t, err)
+	calls := fakeCarthage.calls(t)
+	require.Len(t, calls, 8)
+	assert.Equal(t, "start bootstrap --platform ios --use-ssh --no-build", calls[0])
+	assert.Equal(t, "end bootstrap --platform ios --use-ssh --no-build", calls[1])
+	assert.ElementsMatch(t, []string{
+		"start build --platform ios --cache-builds Alamofire", "end build --platform ios --cache-builds Alamofire",
+		"start build --platform ios --cache-builds RxSwift", "end build --platform ios --cache-builds RxSwift",
+	}, calls[2:6])
+	assert.Equal(t, []string{"start build --platform ios --cache-builds Moya", "end build --platform ios --cache-builds Moya"}, calls[6:])
+}
+
+func Test_GivenIndependentDependencies_WhenBuildInParallelCalled_ThenExpectWorkersLimitRespected(t *testing.T) {
+	// Given
+	fakeCarthage := givenFakeCarthage(t, "")
+	runner := Runner{
+		commandBuilder: fakeCarthage,
+		parallelBuild:  NewParallelBuild(2, false, nil),
+	}
+	graph := dependencyGraph{"A": nil, "B": nil, "C": nil, "D": nil}
+
+	// When
+	err := runner.buildInParallel(graph, []string{"A", "B", "C", "D"})
+
+	// Then
+	require.NoError(t, err)
+	running, maxRunning := 0, 0
+	for _, call := range fakeCarthage.calls(t) {
+		if strings.HasPrefix(call, "start") {
+			running++
+		} else {
+			running--
+		}
+		if running > maxRunning {
+			maxRunning = running
+		}
+	}
+	assert.Equal(t, 2, maxRunning)
+}
+
+func Test_GivenFailingDependency_WhenBuildInParallelCalled_ThenExpectFailureHandledByMode(t *testing.T) {
+	testScenarios := []struct {
+		name          string
+		keepGoing     bool
+		expectedBuilt []string
+		expectedErr   string
+	}{
+		{
+			name:          "fail fast",
+			keepGoing:     false,
+			expectedBuilt: nil,
+			expectedErr:   "failed to build Alamofire, error: exit status 1, not built: Moya, RxSwift",
+		},
+		{
+			name:          "keep going",
+			keepGoing:     true,
+			expectedBuilt: []string{"RxSwift"},
+			expectedErr:   "failed to build Alamofire, error: exit status 1, not built: Moya",
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		// Given
+		fakeCarthage := givenFakeCarthage(t, "Alamofire")
+		runner := Runner{
+			commandBuilder: fakeCarthage,
+			parallelBuild:  NewParallelBuild(2, testScenario.keepGoing, nil),
+		}
+		graph := dependencyGraph{"Alamofire": nil, "Moya": {"Alamofire"}, "RxSwift": {"Alamofire"}}
+		if testScenario.keepGoing {
+			graph["RxSwift"] = nil
+		}
+
+		// When
+		err := runner.buildInParallel(graph, []string{"Alamofire", "Moya", "RxSwift"})
+
+		// Then
+		require.Error(t, err, testScenario.name)
+		assert.Equal(t, testScenario.expectedErr, err.Error(), testScenario.name)
+		var runnerErr *RunnerError
+		require.True(t, errors.As(err, &runnerErr), testScenario.name)
+		assert.Contains(t, runnerErr.Output, "build failed", testScenario.name)
+
+		var built []string
+		for _, call := range fakeCarthage.calls(t) {
+			if name := strings.TrimPrefix(call, "end build --cache-builds "); name != call {
+				built = append(built, name)
+			}
+		}
+		assert.Equal(t, testScenario.expectedBuilt, built, testScenario.name)
+	}
+}
+
+func Test_GivenDependencyCycle_WhenBuildInParallelCalled_ThenExpectError(t *testing.T) {
+	// Given
+	fakeCarthage := givenFakeCarthage(t, "")
+	runner := Runner{
+		commandBuilder: fakeCarthage,
+		parallelBuild:  NewParallelBuild(2, false, nil),
+	}
+	graph := dependencyGraph{"A": {"B"}, "B": {"A"}, "C": nil}
+
+	// When
+	err := runner.buildInParallel(graph, []string{"A", "B", "C"})
+
+	// Then
+	assert.EqualError(t, err, "dependency cycle among: A, B")
+}
+
+func Test_GivenSharedDependency_WhenBuildInParallelCalled_ThenExpectSharedDependencyBuiltOnce(t *testing.T) {
+	// Given
+	fakeCarthage := givenCachingFakeCarthage(t, map[string][]string{
+		"Moya":   {"Alamofire"},
+		"RxMoya": {"Alamofire", "Moya"},
+	})
+	runner := Runner{
+		commandBuilder: fakeCarthage,
+		parallelBuild:  NewParallelBuild(2, false, []string{"--platform", "ios"}),
+	}
+	graph := dependencyGraph{"Alamofire": nil, "Moya": {"Alamofire"}, "RxMoya": {"Alamofire", "Moya"}}
+
+	// When
+	err := runner.buildInParallel(graph, []string{"Alamofire", "Moya", "RxMoya"})
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"built Alamofire", "built Moya", "built RxMoya"}, fakeCarthage.calls(t))
+}
+
+func Test_GivenOutputWithoutTrailingNewline_WhenPrefixWriterFlushed_ThenExpectEveryLinePrefixed(t *testing.T) {
+	// Given
+	var output bytes.Buffer
+	writer := &prefixWriter{prefix: "[Moya] ", writer: &output, lock: &sync.Mutex{}}
+
+	// When
+	_, err := writer.Write([]byte("*** Building scheme\n*** Build"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("ing Moya\ndone"))
+	require.NoError(t, err)
+	writer.flush()
+
+	// Then
+	assert.Equal(t, "[Moya] *** Building scheme\n[Moya] *** Building Moya\n[Moya] done\n", output.String())
+}
+
+// fakeCarthageBuilder builds the commands of a fake carthage executable, which records its calls.
+type fakeCarthageBuilder struct {
+	executable string
+	callsPath  string
+	args       []string
+}
+
+// givenFakeCarthage creates a fake carthage executable, which fails to build the failingDependency.
+func givenFakeCarthage(t *testing.T, failingDependency string) fakeCarthageBuilder {
+	dir := t.TempDir()
+	callsPath := filepath.Join(dir, "calls")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "start $*" >> %[1]s
+if [ "$1" = "build" ] && [ "$(eval echo \${$#})" = "%[2]s" ]; then
+  echo "build failed" 1>&2
+  exit 1
+fi
+sleep 0.2
+echo "end $*" >> %[1]s
+`, callsPath, failingDependency)
+	executable := filepath.Join(dir, "carthage")
+	require.NoError(t, ioutil.WriteFile(executable, []byte(script), 0777))
+	return fakeCarthageBuilder{executable: executable, callsPath: callsPath}
+}
+
+// givenCachingFakeCarthage creates a fake carthage executable, which records the dependencies it builds.
+// Like Carthage, building a dependency builds its dependencies too, unless they are already built and `--cache-builds` is given.
+func givenCachingFakeCarthage(t *testing.T, dependencies map[string][]string) fakeCarthageBuilder {
+	dir := t.TempDir()
+	callsPath := filepath.Join(dir, "calls")
+	builtDir := filepath.Join(dir, "built")
+	dependenciesDir := filepath.Join(dir, "dependencies")
+	require.NoError(t, os.MkdirAll(builtDir, 0777))
+	require.NoError(t, os.MkdirAll(dependenciesDir, 0777))
+	for name, nested := range dependencies {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dependenciesDir, name), []byte(strings.Join(nested, " ")), 0666))
+	}
+
+	script := fmt.Sprintf(`#!/bin/sh
+name=$(eval echo \${$#})
+cache_builds=no
+for arg in "$@"; do
+  [ "$arg" = "--cache-builds" ] && cache_builds=yes
+done
+for dependency in $(cat %[2]s/$name 2>/dev/null) $name; do
+  if [ "$cache_builds" = "yes" ] && [ -f %[3]s/$dependency ]; then
+    continue
+  fi
+  touch %[3]s/$dependency
+  echo "built $dependency" >> %[1]s
+done
+`, callsPath, dependenciesDir, builtDir)
+	executable := filepath.Join(dir, "carthage")
+	require.NoError(t, ioutil.WriteFile(executable, []byte(script), 0777))
+	return fakeCarthageBuilder{executable: executable, callsPath: callsPath}
+}
+
+func (builder fakeCarthageBuilder) AddGitHubToken(stepconf.Secret) CommandBuilder {
+	return builder
+}
+
+func (builder fakeCarthageBuilder) AddXCConfigFile(string) CommandBuilder {
+	return builder
+}
+
+func (builder fakeCarthageBuilder) AddEnvs(...string) CommandBuilder {
+	return builder
+}
+
+func (builder fakeCarthageBuilder) Append(args ...string) CommandBuilder {
+	builder.args = append(append([]string{}, builder.args...), args...)
+	return builder
+}
+
+func (builder fakeCarthageBuilder) Command() *command.Model {
+	return command.New(builder.executable, builder.args...)
+}
+
+func (builder fakeCarthageBuilder) calls(t *testing.T) []string {
+	content, err := ioutil.ReadFile(builder.callsPath)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
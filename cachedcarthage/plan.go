@@ -0,0 +1,154 @@
+package cachedcarthage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// plannedCommand is a Carthage command Run would execute.
+type plannedCommand struct {
+	options      []string
+	dependencies []string
+}
+
+// restoreLocator is a CarthageCache restored by the step itself, like Cache with a RemoteFileCache.
+type restoreLocator interface {
+	RestoreLocation() string
+}
+
+// Plan prints what Run would do: whether the cache is available and why, the Carthage commands with the envs they get,
+// and the paths which would be cached. It does not run Carthage, write any indicator or commit the cache.
+func (runner Runner) Plan() error {
+	fmt.Println()
+	log.Infof("Dry run: Carthage is not executed and the cache is not committed")
+	if located, ok := runner.cache.(restoreLocator); ok {
+		if location := located.RestoreLocation(); location != "" {
+			log.Printf("The cache would be restored from %s, the cache availability below is checked without restoring it", location)
+		}
+	}
+
+	cacheHit := false
+	var dependencies []string
+	if runner.carthageCommand == bootstrapCommand {
+		if err := runner.checkRequirements(); err != nil {
+			return err
+		}
+
+		if cacheHit = runner.isCacheAvailable(); !cacheHit {
+			log.Warnf("Cache not available")
+			dependencies = runner.outdatedDependencies()
+		}
+	}
+
+	fmt.Println()
+	log.Infof("Plan:")
+
+	var commands []plannedCommand
+	switch {
+	case cacheHit:
+		log.Printf("Cache hit, the Carthage command would be skipped")
+	case runner.carthageCommand == bootstrapCommand && runner.offline:
+		if runner.artifactCache != nil && runner.buildsDependencies() {
+			log.Printf("Cache miss, the step would fail in offline mode, unless every dependency is restored from the artifact store")
+		} else {
+			log.Printf("Cache miss, the step would fail in offline mode")
+		}
+	case runner.carthageCommand == bootstrapCommand:
+		if runner.artifactCache != nil && runner.buildsDependencies() {
+			log.Printf("Cache miss, dependencies available in the artifact store would be restored, and only the rest built")
+		} else {
+			log.Printf("Cache miss")
+		}
+	}
+
+	offlineCacheMiss := !cacheHit && runner.carthageCommand == bootstrapCommand && runner.offline
+	if !cacheHit && !offlineCacheMiss {
+		commands = runner.plannedCommands(dependencies)
+	}
+	for _, planned := range commands {
+		runner.printPlannedCommand(planned)
+	}
+	if runner.isDriftGuarded() {
+		log.Printf("Then the %s would be compared with the current one, allowed to change: %s", resolvedFileName, runner.allowedDependenciesDescription())
+	}
+	if !cacheHit && !offlineCacheMiss && runner.isParallelBuild() {
+		log.Printf("Then the dependencies would be built in dependency order, %d at a time, each with:", runner.parallelBuild.Workers)
+		log.Printf("$ %s", runner.redact(runner.newBuildCommand("<dependency>").PrintableCommandArgs()))
+	}
+
+	if runner.carthageCommand == bootstrapCommand {
+		paths, err := runner.cache.IncludePaths(runner.isSplit())
+		if err != nil {
+			log.Warnf("Failed to determine the cached paths, error: %s", err)
+			return nil
+		}
+		log.Printf("Paths which would be cached:")
+		for _, pth := range paths {
+			log.Printf("- %s", pth)
+		}
+	}
+
+	return nil
+}
+
+// plannedCommands returns the Carthage commands Run would execute to build the dependencies (every dependency if empty).
+func (runner Runner) plannedCommands(dependencies []string) []plannedCommand {
+	if !runner.isSplit() {
+		if runner.isParallelBuild() {
+			return []plannedCommand{{options: []string{noBuildArg}, dependencies: dependencies}}
+		}
+		return []plannedCommand{{dependencies: dependencies}}
+	}
+
+	var commands []plannedCommand
+	available, err := runner.cache.IsCheckoutsAvailable()
+	if err != nil {
+		log.Warnf("Failed to check if checkouts are available, error: %s", err)
+	}
+	if available {
+		log.Printf("Cached checkouts would be reused")
+	} else {
+		commands = append(commands, plannedCommand{options: []string{noBuildArg}})
+	}
+	if runner.isParallelBuild() {
+		return commands
+	}
+	return append(commands, plannedCommand{options: []string{noCheckoutArg}, dependencies: dependencies})
+}
+
+func (runner Runner) printPlannedCommand(planned plannedCommand) {
+	cmd := runner.newCommand(planned.options, planned.dependencies)
+
+	log.Printf("$ %s", runner.redact(cmd.PrintableCommandArgs()))
+	for _, env := range injectedEnvs(cmd) {
+		log.Printf("  with %s", runner.redact(env))
+	}
+}
+
+// redact replaces the secrets in the text.
+func (runner Runner) redact(text string) string {
+	if runner.githubAccessToken == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, string(runner.githubAccessToken), runner.githubAccessToken.String())
+}
+
+// injectedEnvs returns the envs of the command which are not inherited from the step's environment.
+func injectedEnvs(cmd *command.Model) []string {
+	inherited := map[string]bool{}
+	for _, env := range os.Environ() {
+		inherited[env] = true
+	}
+
+	var envs []string
+	for _, env := range cmd.GetCmd().Env {
+		if !inherited[env] {
+			envs = append(envs, env)
+		}
+	}
+	return envs
+}
@@ -0,0 +1,90 @@
+package cachedcarthage
+
+import (
+	"testing"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_GivenBootstrapCommandAndCacheNotAvailable_WhenPlanCalled_ThenExpectNothingExecutedOrCommitted(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds([]string{"Alamofire"}).
+		GivenIncludePathsSucceeds([]string{"/awesomepath/Carthage -> /awesomepath/Carthage/Cachefile"})
+	mockArtifactCache := givenMockDependencyArtifactCache()
+	mockCommandBuilder := givenStubbedCommandBuilderReturnFailingCommand()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		artifactCache:   mockArtifactCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	err := runner.Plan()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"Alamofire"})
+	mockCarthageCache.AssertCalled(t, "IncludePaths", false)
+	mockCarthageCache.AssertNotCalled(t, "CreateIndicator")
+	mockCarthageCache.AssertNotCalled(t, "Commit")
+	mockArtifactCache.AssertNotCalled(t, "Restore", mock.Anything)
+}
+
+func Test_GivenBootstrapCommandAndCacheAvailable_WhenPlanCalled_ThenExpectNoCommandPlanned(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(true).
+		GivenIncludePathsSucceeds(nil)
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	err := runner.Plan()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertNotCalled(t, "Command")
+	mockCarthageCache.AssertNotCalled(t, "Commit")
+}
+
+func Test_GivenSplitBootstrapAndCheckoutsNotAvailable_WhenPlannedCommandsCalled_ThenExpectCheckoutAndBuild(t *testing.T) {
+	// Given
+	runner := Runner{
+		carthageCommand:       "bootstrap",
+		cache:                 givenMockCarthageCache().GivenIsCheckoutsAvailableSucceeds(false),
+		splitCheckoutAndBuild: true,
+	}
+
+	// When
+	commands := runner.plannedCommands([]string{"Alamofire"})
+
+	// Then
+	assert.Equal(t, []plannedCommand{
+		{options: []string{"--no-build"}},
+		{options: []string{"--no-checkout"}, dependencies: []string{"Alamofire"}},
+	}, commands)
+}
+
+func Test_GivenCommandWithGitHubToken_WhenRedactingInjectedEnvs_ThenExpectTokenRedacted(t *testing.T) {
+	// Given
+	runner := Runner{githubAccessToken: "ghp_secret"}
+	cmd := command.New("carthage", "bootstrap").AppendEnvs("GITHUB_ACCESS_TOKEN=ghp_secret", "XCODE_XCCONFIG_FILE=/tmp/a.xcconfig")
+
+	// When
+	var envs []string
+	for _, env := range injectedEnvs(cmd) {
+		envs = append(envs, runner.redact(env))
+	}
+
+	// Then
+	assert.Equal(t, []string{"GITHUB_ACCESS_TOKEN=*****", "XCODE_XCCONFIG_FILE=/tmp/a.xcconfig"}, envs)
+}
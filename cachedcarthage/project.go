@@ -1,12 +1,26 @@
 package cachedcarthage
 
-import "path/filepath"
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/fileutil"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
 
 const (
-	carthageDirName  = "Carthage"
-	buildDirName     = "Build"
-	resolvedFileName = "Cartfile.resolved"
-	cacheFileName    = "Cachefile"
+	carthageDirName     = "Carthage"
+	buildDirName        = "Build"
+	checkoutsDirName    = "Checkouts"
+	cartfileName        = "Cartfile"
+	privateCartfileName = "Cartfile.private"
+	resolvedFileName    = "Cartfile.resolved"
+	cacheFileName       = "Cachefile"
+	// checkoutsCacheFileName is the indicator of the Carthage/Checkouts dir, if it is cached separately from the build products.
+	checkoutsCacheFileName = "CheckoutsCachefile"
+	// carthageKitCacheFileName is the indicator of the cached CarthageKit dependency and binary caches.
+	carthageKitCacheFileName = "CarthageKitCachefile"
 )
 
 // Project represents a cached Carthage project.
@@ -31,6 +45,60 @@ func (project Project) buildDir() string {
 	return filepath.Join(project.carthageDir(), buildDirName)
 }
 
+func (project Project) checkoutsDir() string {
+	return filepath.Join(project.carthageDir(), checkoutsDirName)
+}
+
+func (project Project) checkoutsCacheFilePath() string {
+	return filepath.Join(project.carthageDir(), checkoutsCacheFileName)
+}
+
+func (project Project) carthageKitCacheFilePath() string {
+	return filepath.Join(project.carthageDir(), carthageKitCacheFileName)
+}
+
 func (project Project) resolvedFilePath() string {
 	return filepath.Join(project.projectDir, resolvedFileName)
 }
+
+func (project Project) cartfilePath() string {
+	return filepath.Join(project.projectDir, cartfileName)
+}
+
+func (project Project) privateCartfilePath() string {
+	return filepath.Join(project.projectDir, privateCartfileName)
+}
+
+// resolvedDependencyNames returns the names of the dependencies pinned in the project's Cartfile.resolved.
+func (project Project) resolvedDependencyNames() ([]string, error) {
+	content, exists, err := readFileIfExists(project.resolvedFilePath())
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	resolved, err := cartfile.ParseResolved(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s, error: %s", resolvedFileName, err)
+	}
+
+	var names []string
+	for _, dependency := range resolved.Dependencies {
+		names = append(names, dependency.Name())
+	}
+	return names, nil
+}
+
+// readFileIfExists reads the given file, the returned bool is false if the file does not exist.
+func readFileIfExists(pth string) (string, bool, error) {
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return "", false, err
+	} else if !exist {
+		return "", false, nil
+	}
+
+	content, err := fileutil.ReadStringFromFile(pth)
+	if err != nil {
+		return "", false, err
+	}
+	return content, true, nil
+}
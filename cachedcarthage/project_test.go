@@ -53,3 +53,51 @@ func Test_WhenResolvedFilePathCalled_ThenExpectCorrectPath(t *testing.T) {
 	// Then
 	assert.Equal(t, expectedPath, actualPath)
 }
+
+func Test_WhenCartfilePathCalled_ThenExpectCorrectPath(t *testing.T) {
+	// Given
+	expectedPath := "/base/dir/Cartfile"
+	project := Project{"/base/dir"}
+
+	// When
+	actualPath := project.cartfilePath()
+
+	// Then
+	assert.Equal(t, expectedPath, actualPath)
+}
+
+func Test_WhenPrivateCartfilePathCalled_ThenExpectCorrectPath(t *testing.T) {
+	// Given
+	expectedPath := "/base/dir/Cartfile.private"
+	project := Project{"/base/dir"}
+
+	// When
+	actualPath := project.privateCartfilePath()
+
+	// Then
+	assert.Equal(t, expectedPath, actualPath)
+}
+
+func Test_WhenCheckoutsDirCalled_ThenExpectCorrectPath(t *testing.T) {
+	// Given
+	expectedPath := "/base/dir/Carthage/Checkouts"
+	project := Project{"/base/dir"}
+
+	// When
+	actualPath := project.checkoutsDir()
+
+	// Then
+	assert.Equal(t, expectedPath, actualPath)
+}
+
+func Test_WhenCheckoutsCacheFilePathCalled_ThenExpectCorrectPath(t *testing.T) {
+	// Given
+	expectedPath := "/base/dir/Carthage/CheckoutsCachefile"
+	project := Project{"/base/dir"}
+
+	// When
+	actualPath := project.checkoutsCacheFilePath()
+
+	// Then
+	assert.Equal(t, expectedPath, actualPath)
+}
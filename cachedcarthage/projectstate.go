@@ -1,5 +1,7 @@
 package cachedcarthage
 
+import "github.com/bitrise-steplib/steps-carthage/cartfile"
+
 // ProjectState represents a snapshot of a cached Carthage project.
 type ProjectState struct {
 	buildDirNotEmpty bool
@@ -7,10 +9,31 @@ type ProjectState struct {
 	cacheFileExists  bool
 	cacheFileContent string
 
-	resolvedFileExists  bool
-	resolvedFileContent string
+	resolvedFileExists   bool
+	resolvedFileContent  string
+	resolvedDependencies []cartfile.ResolvedDependency
 
 	carthageDirExists bool
+
+	checkoutsDirNotEmpty      bool
+	checkoutsCacheFileExists  bool
+	checkoutsCacheFileContent string
+
+	// brokenDependencies are the resolved dependencies whose build products in Carthage/Build are missing or do not match the pinned version.
+	brokenDependencies []brokenDependency
+}
+
+type brokenDependency struct {
+	name   string
+	reason string
+}
+
+func (state ProjectState) brokenDependencyNames() []string {
+	var names []string
+	for _, dependency := range state.brokenDependencies {
+		names = append(names, dependency.name)
+	}
+	return names
 }
 
 func (state ProjectState) isCacheIntact() bool {
@@ -18,3 +41,9 @@ func (state ProjectState) isCacheIntact() bool {
 		state.cacheFileExists &&
 		state.resolvedFileExists
 }
+
+func (state ProjectState) isCheckoutsCacheIntact() bool {
+	return state.checkoutsDirNotEmpty &&
+		state.checkoutsCacheFileExists &&
+		state.resolvedFileExists
+}
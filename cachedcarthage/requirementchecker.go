@@ -0,0 +1,58 @@
+package cachedcarthage
+
+import (
+	"fmt"
+
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+// RequirementCheckMode tells what to do when the Cartfile.resolved does not satisfy the Cartfile requirements.
+type RequirementCheckMode string
+
+// Requirement check modes.
+const (
+	RequirementCheckFail     RequirementCheckMode = "fail"
+	RequirementCheckWarn     RequirementCheckMode = "warn"
+	RequirementCheckDisabled RequirementCheckMode = "no"
+)
+
+// ProjectRequirementChecker checks the Cartfile.resolved of a Carthage project
+// against the requirements declared in its Cartfile and Cartfile.private.
+type ProjectRequirementChecker struct {
+	project Project
+}
+
+// NewProjectRequirementChecker ...
+func NewProjectRequirementChecker(project Project) ProjectRequirementChecker {
+	return ProjectRequirementChecker{project: project}
+}
+
+// Check returns the check result of every requirement, or nothing if the project has no Cartfile.resolved.
+func (checker ProjectRequirementChecker) Check() ([]cartfile.CheckResult, error) {
+	resolvedContent, exists, err := readFileIfExists(checker.project.resolvedFilePath())
+	if err != nil || !exists {
+		return nil, err
+	}
+	resolved, err := cartfile.ParseResolved(resolvedContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s, error: %s", resolvedFileName, err)
+	}
+
+	var requirements []cartfile.Requirement
+	for _, pth := range []string{checker.project.cartfilePath(), checker.project.privateCartfilePath()} {
+		content, exists, err := readFileIfExists(pth)
+		if err != nil {
+			return nil, err
+		} else if !exists {
+			continue
+		}
+
+		file, err := cartfile.ParseRequirements(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s, error: %s", pth, err)
+		}
+		requirements = append(requirements, file.Requirements...)
+	}
+
+	return cartfile.CheckResolved(requirements, resolved), nil
+}
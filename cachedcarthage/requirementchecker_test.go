@@ -0,0 +1,74 @@
+package cachedcarthage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenNoResolvedFile_WhenCheckCalled_ThenExpectNoResults(t *testing.T) {
+	// Given
+	tempDir := givenTempDir(t)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	givenFile(t, filepath.Join(tempDir, "Cartfile"), `github "Alamofire/Alamofire" ~> 5.4`)
+	checker := NewProjectRequirementChecker(NewProject(tempDir))
+
+	// When
+	results, err := checker.Check()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func Test_GivenCartfileAndPrivateCartfile_WhenCheckCalled_ThenExpectResultsForBoth(t *testing.T) {
+	// Given
+	tempDir := givenTempDir(t)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	givenFile(t, filepath.Join(tempDir, "Cartfile"), `github "Alamofire/Alamofire" ~> 5.4`)
+	givenFile(t, filepath.Join(tempDir, "Cartfile.private"), `github "Quick/Nimble" ~> 9.0`)
+	givenFile(t, filepath.Join(tempDir, "Cartfile.resolved"), `github "Alamofire/Alamofire" "5.4.1"
+github "Quick/Nimble" "8.1.2"
+`)
+	checker := NewProjectRequirementChecker(NewProject(tempDir))
+
+	// When
+	results, err := checker.Check()
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, cartfile.CheckSatisfied, results[0].Status)
+	assert.Equal(t, cartfile.CheckViolated, results[1].Status)
+}
+
+func Test_GivenInvalidCartfile_WhenCheckCalled_ThenExpectError(t *testing.T) {
+	// Given
+	tempDir := givenTempDir(t)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDir))
+	}()
+	givenFile(t, filepath.Join(tempDir, "Cartfile"), `github "Alamofire/Alamofire" ~> five`)
+	givenFile(t, filepath.Join(tempDir, "Cartfile.resolved"), `github "Alamofire/Alamofire" "5.4.1"`)
+	checker := NewProjectRequirementChecker(NewProject(tempDir))
+
+	// When
+	_, err := checker.Check()
+
+	// Then
+	assert.Error(t, err)
+}
+
+// helpers
+func givenFile(t *testing.T, pth, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0777))
+	require.NoError(t, os.WriteFile(pth, []byte(content), 0666))
+}
@@ -0,0 +1,257 @@
+package cachedcarthage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
+)
+
+// projectOrigin is the origin of the root node of the ResolvedGraph, the project itself.
+const projectOrigin = "project"
+
+// projectNodeIDPrefix prefixes the project name in the ID of the root node. Dependency names never contain a slash,
+// so the root node can not be mistaken for a dependency named like the project.
+const projectNodeIDPrefix = "./"
+
+// ResolvedGraph is the graph of the dependencies pinned in the Cartfile.resolved of a Carthage project.
+// The edges of the project are read from its Cartfile and Cartfile.private, the edges of a dependency from the Cartfile
+// of its checkout. The Cartfile.private of a checkout is not read, as Carthage does not build the private dependencies of dependencies.
+type ResolvedGraph struct {
+	// Root is the ID of the project node.
+	Root  string      `json:"root"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Conflicts are the dependencies pinned in the Cartfile.resolved of a checkout at a different version than in the project's one.
+	Conflicts []PinConflict `json:"conflicts"`
+}
+
+// GraphNode is the project or a dependency pinned in its Cartfile.resolved.
+type GraphNode struct {
+	// ID is the name of a dependency, or the project name prefixed with projectNodeIDPrefix. The edges refer to the nodes by ID.
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Origin string `json:"origin"`
+	URL    string `json:"url,omitempty"`
+	Pin    string `json:"pin,omitempty"`
+}
+
+// GraphEdge tells that a node depends on another one, with the requirement of its Cartfile,
+// and the pin of the Cartfile.resolved of its checkout, if it has one.
+type GraphEdge struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Requirement string `json:"requirement,omitempty"`
+	Pin         string `json:"pin,omitempty"`
+}
+
+// PinConflict is a dependency pinned in the Cartfile.resolved of checkouts at a different version than in the project's one.
+type PinConflict struct {
+	Dependency string      `json:"dependency"`
+	Pin        string      `json:"pin"`
+	NestedPins []NestedPin `json:"nested_pins"`
+}
+
+// NestedPin is the pin of a dependency in the Cartfile.resolved of a checkout.
+type NestedPin struct {
+	PinnedBy string `json:"pinned_by"`
+	Pin      string `json:"pin"`
+}
+
+// String ...
+func (conflict PinConflict) String() string {
+	var nested []string
+	for _, pin := range conflict.NestedPins {
+		nested = append(nested, fmt.Sprintf("%s by %s", pin.Pin, pin.PinnedBy))
+	}
+	return fmt.Sprintf("%s is pinned at %s, but at %s in nested Cartfile.resolved files", conflict.Dependency, conflict.Pin, strings.Join(nested, ", "))
+}
+
+// CollectResolvedGraph reads the graph of the project's dependencies. The graph has only the project node
+// if the project has no Cartfile.resolved, and the dependencies without a checkout (like binary dependencies) have no edges.
+func CollectResolvedGraph(project Project) (ResolvedGraph, error) {
+	name := filepath.Base(project.projectDir)
+	root := projectNodeIDPrefix + name
+	graph := ResolvedGraph{
+		Root:      root,
+		Nodes:     []GraphNode{{ID: root, Name: name, Origin: projectOrigin}},
+		Edges:     []GraphEdge{},
+		Conflicts: []PinConflict{},
+	}
+
+	content, exists, err := readFileIfExists(project.resolvedFilePath())
+	if err != nil || !exists {
+		return graph, err
+	}
+	resolved, err := cartfile.ParseResolved(content)
+	if err != nil {
+		return ResolvedGraph{}, fmt.Errorf("failed to parse %s, error: %s", resolvedFileName, err)
+	}
+
+	pins := map[string]string{}
+	for _, dependency := range resolved.Dependencies {
+		pins[dependency.Name()] = dependency.Pin
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:     dependency.Name(),
+			Name:   dependency.Name(),
+			Origin: string(dependency.Origin),
+			URL:    dependency.URL(),
+			Pin:    dependency.Pin,
+		})
+	}
+
+	for _, pth := range []string{project.cartfilePath(), project.privateCartfilePath()} {
+		edges, err := requirementEdges(root, pth, pins)
+		if err != nil {
+			return ResolvedGraph{}, err
+		}
+		graph.Edges = append(graph.Edges, edges...)
+	}
+
+	nestedPins := map[string][]NestedPin{}
+	for _, dependency := range resolved.Dependencies {
+		name := dependency.Name()
+		checkoutDir := filepath.Join(project.checkoutsDir(), name)
+
+		edges, err := requirementEdges(name, filepath.Join(checkoutDir, cartfileName), pins)
+		if err != nil {
+			return ResolvedGraph{}, err
+		}
+		if edges, err = withNestedPins(edges, filepath.Join(checkoutDir, resolvedFileName)); err != nil {
+			return ResolvedGraph{}, err
+		}
+
+		for _, edge := range edges {
+			if edge.Pin != "" && edge.Pin != pins[edge.To] {
+				nestedPins[edge.To] = append(nestedPins[edge.To], NestedPin{PinnedBy: name, Pin: edge.Pin})
+			}
+		}
+		graph.Edges = append(graph.Edges, edges...)
+	}
+
+	for _, dependency := range resolved.Dependencies {
+		if nested, ok := nestedPins[dependency.Name()]; ok {
+			graph.Conflicts = append(graph.Conflicts, PinConflict{Dependency: dependency.Name(), Pin: dependency.Pin, NestedPins: nested})
+		}
+	}
+
+	return graph, nil
+}
+
+// requirementEdges returns the edges of the requirements of the Cartfile, to the pinned dependencies.
+func requirementEdges(from, pth string, pins map[string]string) ([]GraphEdge, error) {
+	content, exists, err := readFileIfExists(pth)
+	if err != nil || !exists {
+		return nil, err
+	}
+	requirements, err := cartfile.ParseRequirements(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s, error: %s", pth, err)
+	}
+
+	var edges []GraphEdge
+	for _, requirement := range requirements.Requirements {
+		if _, ok := pins[requirement.Name()]; !ok {
+			continue
+		}
+		edge := GraphEdge{From: from, To: requirement.Name()}
+		if requirement.Constraint.Operator != cartfile.OperatorAny {
+			edge.Requirement = requirement.Constraint.String()
+		}
+		edges = append(edges, edge)
+	}
+	return edges, nil
+}
+
+// withNestedPins sets the pins of the Cartfile.resolved of a checkout on the edges of its Cartfile.
+// The other dependencies pinned in it, like the private ones of the checkout, do not get an edge.
+func withNestedPins(edges []GraphEdge, pth string) ([]GraphEdge, error) {
+	content, exists, err := readFileIfExists(pth)
+	if err != nil || !exists {
+		return edges, err
+	}
+	resolved, err := cartfile.ParseResolved(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s, error: %s", pth, err)
+	}
+
+	nestedPins := map[string]string{}
+	for _, dependency := range resolved.Dependencies {
+		nestedPins[dependency.Name()] = dependency.Pin
+	}
+	for i, edge := range edges {
+		edges[i].Pin = nestedPins[edge.To]
+	}
+	return edges, nil
+}
+
+// DOT returns the graph in the Graphviz DOT format. The dependencies with pin conflicts and the conflicting edges are red.
+func (graph ResolvedGraph) DOT() string {
+	conflicting := map[string]bool{}
+	for _, conflict := range graph.Conflicts {
+		conflicting[conflict.Dependency] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph \"Carthage dependencies\" {\n")
+	for _, node := range graph.Nodes {
+		attributes := []string{"label=" + strconv.Quote(node.Name)}
+		switch {
+		case node.Origin == projectOrigin:
+			attributes = append(attributes, "shape=box")
+		case node.Pin != "":
+			attributes[0] = "label=" + strconv.Quote(node.Name+"\n"+node.Pin)
+		}
+		if node.Origin != projectOrigin && conflicting[node.Name] {
+			attributes = append(attributes, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", strconv.Quote(node.ID), strings.Join(attributes, ", "))
+	}
+
+	pins := map[string]string{}
+	for _, node := range graph.Nodes {
+		pins[node.ID] = node.Pin
+	}
+	for _, edge := range graph.Edges {
+		var attributes []string
+		switch {
+		case edge.Requirement != "":
+			attributes = append(attributes, "label="+strconv.Quote(edge.Requirement))
+		case edge.Pin != "":
+			attributes = append(attributes, "label="+strconv.Quote(edge.Pin))
+		}
+		if edge.Pin != "" && edge.Pin != pins[edge.To] {
+			attributes = append(attributes, "color=red")
+		}
+
+		line := fmt.Sprintf("  %s -> %s", strconv.Quote(edge.From), strconv.Quote(edge.To))
+		if len(attributes) > 0 {
+			line += fmt.Sprintf(" [%s]", strings.Join(attributes, ", "))
+		}
+		b.WriteString(line + ";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dependencyGraph returns the dependencies of the graph, each with the dependencies it depends on.
+func (graph ResolvedGraph) dependencyGraph() dependencyGraph {
+	dependencies := dependencyGraph{}
+	for _, node := range graph.Nodes {
+		if node.Origin != projectOrigin {
+			dependencies[node.ID] = nil
+		}
+	}
+	for _, edge := range graph.Edges {
+		if edge.From != graph.Root {
+			dependencies[edge.From] = append(dependencies[edge.From], edge.To)
+		}
+	}
+	for name := range dependencies {
+		sort.Strings(dependencies[name])
+	}
+	return dependencies
+}
@@ -0,0 +1,111 @@
+package cachedcarthage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenProjectWithNestedPins_WhenCollectResolvedGraphCalled_ThenExpectGraphWithConflicts(t *testing.T) {
+	// Given
+	project := givenProjectWithCheckouts(t, `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "15.0.0"
+github "ReactiveX/RxSwift" "6.2.0"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+`, map[string]givenCheckout{
+		"Moya": {
+			cartfile: `github "Alamofire/Alamofire" ~> 5.4
+github "ReactiveX/RxSwift" ~> 6.0
+`,
+			// Nimble is a private dependency of Moya
+			resolvedFile: `github "Alamofire/Alamofire" "5.4.0"
+github "ReactiveX/RxSwift" "6.2.0"
+github "Quick/Nimble" "9.2.0"
+`,
+		},
+	})
+	require.NoError(t, ioutil.WriteFile(project.cartfilePath(), []byte(`github "Moya/Moya" ~> 15.0
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json"
+`), 0666))
+	require.NoError(t, ioutil.WriteFile(project.privateCartfilePath(), []byte(`github "Alamofire/Alamofire" == 5.4.1`), 0666))
+	rxSwiftDir := filepath.Join(project.checkoutsDir(), "RxSwift")
+	require.NoError(t, os.MkdirAll(rxSwiftDir, 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rxSwiftDir, cartfileName), []byte(`github "Alamofire/Alamofire" >= 5.0`), 0666))
+
+	// When
+	graph, err := CollectResolvedGraph(project)
+
+	// Then
+	require.NoError(t, err)
+	name := filepath.Base(project.projectDir)
+	root := "./" + name
+	assert.Equal(t, root, graph.Root)
+	assert.Equal(t, []GraphNode{
+		{ID: root, Name: name, Origin: "project"},
+		{ID: "Alamofire", Name: "Alamofire", Origin: "github", URL: "https://github.com/Alamofire/Alamofire.git", Pin: "5.4.1"},
+		{ID: "Moya", Name: "Moya", Origin: "github", URL: "https://github.com/Moya/Moya.git", Pin: "15.0.0"},
+		{ID: "RxSwift", Name: "RxSwift", Origin: "github", URL: "https://github.com/ReactiveX/RxSwift.git", Pin: "6.2.0"},
+		{ID: "FirebaseAnalyticsBinary", Name: "FirebaseAnalyticsBinary", Origin: "binary", URL: "https://dl.google.com/FirebaseAnalyticsBinary.json", Pin: "8.0.0"},
+	}, graph.Nodes)
+	assert.Equal(t, []GraphEdge{
+		{From: root, To: "Moya", Requirement: "~> 15.0"},
+		{From: root, To: "FirebaseAnalyticsBinary"},
+		{From: root, To: "Alamofire", Requirement: "== 5.4.1"},
+		{From: "Moya", To: "Alamofire", Requirement: "~> 5.4", Pin: "5.4.0"},
+		{From: "Moya", To: "RxSwift", Requirement: "~> 6.0", Pin: "6.2.0"},
+		{From: "RxSwift", To: "Alamofire", Requirement: ">= 5.0"},
+	}, graph.Edges)
+	assert.Equal(t, []PinConflict{
+		{Dependency: "Alamofire", Pin: "5.4.1", NestedPins: []NestedPin{{PinnedBy: "Moya", Pin: "5.4.0"}}},
+	}, graph.Conflicts)
+	assert.Equal(t, "Alamofire is pinned at 5.4.1, but at 5.4.0 by Moya in nested Cartfile.resolved files", graph.Conflicts[0].String())
+}
+
+func Test_GivenProjectWithoutResolvedFile_WhenCollectResolvedGraphCalled_ThenExpectProjectNodeOnly(t *testing.T) {
+	// Given
+	project := Project{t.TempDir()}
+
+	// When
+	graph, err := CollectResolvedGraph(project)
+
+	// Then
+	require.NoError(t, err)
+	name := filepath.Base(project.projectDir)
+	assert.Equal(t, []GraphNode{{ID: "./" + name, Name: name, Origin: "project"}}, graph.Nodes)
+	assert.Empty(t, graph.Edges)
+	assert.Empty(t, graph.Conflicts)
+}
+
+func Test_GivenGraphWithConflict_WhenDOTCalled_ThenExpectConflictsHighlighted(t *testing.T) {
+	// Given
+	graph := ResolvedGraph{
+		Root: "./App",
+		Nodes: []GraphNode{
+			{ID: "./App", Name: "App", Origin: "project"},
+			{ID: "Alamofire", Name: "Alamofire", Origin: "github", Pin: "5.4.1"},
+			{ID: "Moya", Name: "Moya", Origin: "github", Pin: "15.0.0"},
+		},
+		Edges: []GraphEdge{
+			{From: "./App", To: "Moya", Requirement: "~> 15.0"},
+			{From: "Moya", To: "Alamofire", Pin: "5.4.0"},
+		},
+		Conflicts: []PinConflict{{Dependency: "Alamofire", Pin: "5.4.1", NestedPins: []NestedPin{{PinnedBy: "Moya", Pin: "5.4.0"}}}},
+	}
+
+	// When
+	dot := graph.DOT()
+
+	// Then
+	assert.Equal(t, `digraph "Carthage dependencies" {
+  "./App" [label="App", shape=box];
+  "Alamofire" [label="Alamofire\n5.4.1", color=red];
+  "Moya" [label="Moya\n15.0.0"];
+  "./App" -> "Moya" [label="~> 15.0"];
+  "Moya" -> "Alamofire" [label="5.4.0", color=red];
+}
+`, dot)
+}
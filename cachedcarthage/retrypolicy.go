@@ -0,0 +1,54 @@
+package cachedcarthage
+
+import (
+	"math"
+	"time"
+)
+
+// maxRetryWait limits how long a retry may wait for a rate limit reset, longer waits fail the step instead.
+const maxRetryWait = 15 * time.Minute
+
+// RetryPolicy tells how many times a failed Carthage command is retried and how long to wait before each retry.
+type RetryPolicy struct {
+	// Count is the number of retries after the first attempt.
+	Count uint
+	// Wait is the delay before the first retry.
+	Wait time.Duration
+	// BackoffFactor multiplies the delay before every further retry.
+	BackoffFactor float64
+}
+
+// NewRetryPolicy ...
+func NewRetryPolicy(count uint, wait time.Duration, backoffFactor float64) RetryPolicy {
+	return RetryPolicy{
+		Count:         count,
+		Wait:          wait,
+		BackoffFactor: backoffFactor,
+	}
+}
+
+// delay returns the delay before the given retry, retries are numbered from 1.
+func (policy RetryPolicy) delay(retry uint) time.Duration {
+	factor := policy.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	return time.Duration(float64(policy.Wait) * math.Pow(factor, float64(retry-1)))
+}
+
+// wait returns how long to wait before the given retry of the failure, and false if the failure should not be retried.
+func (policy RetryPolicy) wait(retry uint, failure retryableFailure) (time.Duration, bool) {
+	if retry > policy.Count {
+		return 0, false
+	}
+
+	wait := policy.delay(retry)
+	if failure.retryAfter > wait {
+		wait = failure.retryAfter
+	}
+	if wait > maxRetryWait {
+		return wait, false
+	}
+	return wait, true
+}
@@ -0,0 +1,61 @@
+package cachedcarthage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GivenBackoffFactor_WhenDelayCalled_ThenExpectExponentialDelay(t *testing.T) {
+	// Given
+	policy := NewRetryPolicy(3, 2*time.Second, 3)
+
+	// Then
+	assert.Equal(t, 2*time.Second, policy.delay(1))
+	assert.Equal(t, 6*time.Second, policy.delay(2))
+	assert.Equal(t, 18*time.Second, policy.delay(3))
+}
+
+func Test_GivenBackoffFactorBelowOne_WhenDelayCalled_ThenExpectConstantDelay(t *testing.T) {
+	// Given
+	policy := NewRetryPolicy(2, 2*time.Second, 0)
+
+	// Then
+	assert.Equal(t, 2*time.Second, policy.delay(1))
+	assert.Equal(t, 2*time.Second, policy.delay(2))
+}
+
+func Test_GivenRetriesExhausted_WhenWaitCalled_ThenExpectNoRetry(t *testing.T) {
+	// Given
+	policy := NewRetryPolicy(1, time.Second, 2)
+
+	// When
+	_, ok := policy.wait(2, retryableFailure{reason: "timeout"})
+
+	// Then
+	assert.False(t, ok)
+}
+
+func Test_GivenRateLimitResetAfterDelay_WhenWaitCalled_ThenExpectWaitUntilReset(t *testing.T) {
+	// Given
+	policy := NewRetryPolicy(1, time.Second, 2)
+
+	// When
+	wait, ok := policy.wait(1, retryableFailure{reason: "GitHub rate limit exceeded", retryAfter: time.Minute})
+
+	// Then
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func Test_GivenRateLimitResetTooFarAway_WhenWaitCalled_ThenExpectNoRetry(t *testing.T) {
+	// Given
+	policy := NewRetryPolicy(1, time.Second, 2)
+
+	// When
+	_, ok := policy.wait(1, retryableFailure{reason: "GitHub rate limit exceeded", retryAfter: time.Hour})
+
+	// Then
+	assert.False(t, ok)
+}
@@ -2,20 +2,34 @@ package cachedcarthage
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bitrise-io/go-steputils/stepconf"
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-utils/retry"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
 )
 
 const (
 	bootstrapCommand = "bootstrap"
 	updateCommand    = "update"
+	buildCommand     = "build"
+	checkoutCommand  = "checkout"
+
+	noBuildArg     = "--no-build"
+	noCheckoutArg  = "--no-checkout"
+	cacheBuildsArg = "--cache-builds"
+
+	// commandOutputTailSize is the size of the end of a Carthage command output kept to tell why the command failed.
+	commandOutputTailSize = 1024 * 1024
 )
 
 // CarthageCache ...
@@ -23,16 +37,35 @@ type CarthageCache interface {
 	Commit() error
 	CreateIndicator() error
 	IsAvailable() (bool, error)
+	OutdatedDependencies() ([]string, error)
+
+	CommitCheckoutsSeparately() error
+	CreateCheckoutsIndicator() error
+	IsCheckoutsAvailable() (bool, error)
+
+	IncludePaths(separateCheckouts bool) ([]string, error)
+}
+
+// DependencyArtifactCache ...
+type DependencyArtifactCache interface {
+	Restore(dependencies []string) ([]string, []string, error)
+	Save(dependencies []string) error
 }
 
 // CommandBuilder ...
 type CommandBuilder interface {
 	AddGitHubToken(githubToken stepconf.Secret) CommandBuilder
 	AddXCConfigFile(path string) CommandBuilder
+	AddEnvs(envs ...string) CommandBuilder
 	Append(args ...string) CommandBuilder
 	Command() *command.Model
 }
 
+// RequirementChecker ...
+type RequirementChecker interface {
+	Check() ([]cartfile.CheckResult, error)
+}
+
 // Runner can be used to execute Carthage command and cache the results.
 type Runner struct {
 	carthageCommand   string
@@ -40,7 +73,59 @@ type Runner struct {
 	githubAccessToken stepconf.Secret
 	xcconfigPath      string
 	cache             CarthageCache
-	commandBuilder    CommandBuilder
+	// artifactCache is consulted before building the dependencies, if set.
+	artifactCache  DependencyArtifactCache
+	commandBuilder CommandBuilder
+
+	requirementChecker   RequirementChecker
+	requirementCheckMode RequirementCheckMode
+
+	retryPolicy RetryPolicy
+	sleepFunc   func(time.Duration)
+
+	splitCheckoutAndBuild bool
+	parallelBuild         ParallelBuild
+	driftGuard            DriftGuard
+	// offline fails the Run on a cache miss, and blocks the network access of the Carthage commands.
+	offline bool
+
+	project        Project
+	deployDir      string
+	outputExporter OutputExporter
+
+	// The fields below are set by RunProjects, if the projects run in parallel.
+	// outputPrefix prefixes every line of the Carthage command output, written holding the outputLock.
+	outputPrefix string
+	outputLock   sync.Locker
+	// checkoutLock is held while checking out the dependencies, as the projects share the CarthageKit dependency repositories.
+	checkoutLock sync.Locker
+}
+
+// runResult describes how the dependencies were provided by a Run.
+type runResult struct {
+	cacheHit bool
+	// restoredDependencies are the dependencies restored from the artifact store.
+	restoredDependencies []string
+	// rebuiltDependencies are the dependencies built by the Carthage command, if only some of them were built.
+	rebuiltDependencies []string
+	rebuiltAll          bool
+	// outdatedReport is set by the outdated command.
+	outdatedReport *OutdatedReport
+	// resolvedDiff is set by the update command, if the DriftGuard is enabled.
+	resolvedDiff *ResolvedDiff
+}
+
+// RunnerOptions are the optional behaviours of the Runner, all disabled by default.
+type RunnerOptions struct {
+	// ArtifactCache is consulted before building the dependencies, if set.
+	ArtifactCache DependencyArtifactCache
+	RetryPolicy   RetryPolicy
+	// SplitCheckoutAndBuild runs the bootstrap command as a checkout and a build, so the checkouts can be cached separately.
+	SplitCheckoutAndBuild bool
+	ParallelBuild         ParallelBuild
+	DriftGuard            DriftGuard
+	// Offline fails the Run on a cache miss, and blocks the network access of the Carthage commands.
+	Offline bool
 }
 
 // NewRunner ...
@@ -51,56 +136,414 @@ func NewRunner(
 	xcconfigPath string,
 	cache CarthageCache,
 	commandBuilder CommandBuilder,
+	requirementChecker RequirementChecker,
+	requirementCheckMode RequirementCheckMode,
+	project Project,
+	deployDir string,
+	outputExporter OutputExporter,
+	options RunnerOptions,
 ) Runner {
 	return Runner{
-		carthageCommand:   carthageCommand,
-		args:              args,
-		githubAccessToken: githubAccessToken,
-		xcconfigPath:      xcconfigPath,
-		cache:             cache,
-		commandBuilder:    commandBuilder,
+		carthageCommand:       carthageCommand,
+		args:                  args,
+		githubAccessToken:     githubAccessToken,
+		xcconfigPath:          xcconfigPath,
+		cache:                 cache,
+		artifactCache:         options.ArtifactCache,
+		commandBuilder:        commandBuilder,
+		requirementChecker:    requirementChecker,
+		requirementCheckMode:  requirementCheckMode,
+		retryPolicy:           options.RetryPolicy,
+		splitCheckoutAndBuild: options.SplitCheckoutAndBuild,
+		parallelBuild:         options.ParallelBuild,
+		driftGuard:            options.DriftGuard,
+		offline:               options.Offline,
+		project:               project,
+		deployDir:             deployDir,
+		outputExporter:        outputExporter,
 	}
 }
 
 // Run ...
 func (runner Runner) Run() error {
+	startTime := time.Now()
+	result, err := runner.run()
+	runner.exportOutputs(result, time.Since(startTime), failureReason(err))
+
+	return err
+}
+
+// failureReason returns the reason of the Run failure, empty if the Run succeeded.
+func failureReason(err error) FailureReason {
+	if err == nil {
+		return ""
+	}
+
+	var runnerErr *RunnerError
+	if errors.As(err, &runnerErr) {
+		return runnerErr.Reason
+	}
+	return FailureReasonUnknown
+}
+
+func (runner Runner) run() (runResult, error) {
+	var dependencies, restoredDependencies []string
+
+	if runner.carthageCommand == outdatedCommand {
+		return runner.reportOutdated()
+	}
 
 	if runner.carthageCommand == bootstrapCommand {
+		if err := runner.checkRequirements(); err != nil {
+			return runResult{}, err
+		}
+
 		if runner.isCacheAvailable() {
 			log.Donef("Cache available")
 
 			log.Infof("Committing Cachefile...")
-			err := runner.cache.Commit()
+			err := runner.commitCache()
 			if err == nil {
 				log.Donef("Using cached dependencies for bootstrap command. If you would like to force update your dependencies, select `update` as CarthageCommand and re-run your build.")
-				return nil
+				return runResult{cacheHit: true}, nil
 			}
 
 			log.Warnf("Cache collection skipped: %s", err)
 		} else {
 			log.Warnf("Cache not available")
+
+			dependencies = runner.outdatedDependencies()
+
+			var restoredAll bool
+			dependencies, restoredDependencies, restoredAll = runner.restoreArtifacts(dependencies)
+			if restoredAll {
+				return runResult{restoredDependencies: restoredDependencies}, runner.cacheBootstrapResults()
+			}
+
+			if runner.offline {
+				return runResult{restoredDependencies: restoredDependencies}, runner.offlineCacheMissError(dependencies)
+			}
 		}
 	}
 
-	if err := runner.perform(); err != nil {
-		if runnerErr, ok := err.(*RunnerError); ok {
-			runnerErr.Err = fmt.Errorf("Carthage command failed, error: %s", runnerErr.Err)
+	guarded := runner.isDriftGuarded()
+	var resolvedBefore []cartfile.ResolvedDependency
+	if guarded {
+		snapshot, err := runner.snapshotResolvedDependencies()
+		if err != nil {
+			if runner.driftGuard.Mode == ResolvedDriftFail {
+				return runResult{}, err
+			}
+			log.Warnf("Failed to snapshot %s, changes are not checked, error: %s", resolvedFileName, err)
+			guarded = false
 		}
+		resolvedBefore = snapshot
+	}
 
-		return err
+	var options []string
+	if runner.isSplit() {
+		if err := runner.checkout(); err != nil {
+			return runResult{}, runner.commandFailed(err)
+		}
+		options = []string{noCheckoutArg}
+	}
+
+	if err := runner.build(options, dependencies); err != nil {
+		return runResult{}, runner.commandFailed(err)
+	}
+
+	result := runResult{restoredDependencies: restoredDependencies, rebuiltDependencies: dependencies}
+	if len(dependencies) == 0 {
+		result.rebuiltAll = runner.buildsDependencies()
+	}
+
+	if guarded {
+		diff, err := runner.checkResolvedDrift(resolvedBefore)
+		result.resolvedDiff = diff
+		if err != nil {
+			return result, err
+		}
 	}
 
 	if runner.carthageCommand == bootstrapCommand {
-		log.Infof("Creating cache indicator")
-		if err := runner.cache.CreateIndicator(); err != nil {
-			return err
+		runner.saveArtifacts(dependencies)
+
+		if err := runner.cacheBootstrapResults(); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (runner Runner) cacheBootstrapResults() error {
+	log.Infof("Creating cache indicator")
+	if err := runner.cache.CreateIndicator(); err != nil {
+		return err
+	}
+
+	if err := runner.commitCache(); err != nil {
+		log.Warnf("Cache committing skipped: %s", err)
+	}
+	return nil
+}
+
+// restoreArtifacts restores the dependencies to build (every dependency if empty) from the artifact store,
+// and returns the dependencies left to build, the restored ones, and whether every dependency was restored.
+func (runner Runner) restoreArtifacts(dependencies []string) ([]string, []string, bool) {
+	if runner.artifactCache == nil || !runner.buildsDependencies() {
+		return dependencies, nil, false
+	}
+
+	log.Infof("Check if build products are available in the artifact store")
+
+	restored, missing, err := runner.artifactCache.Restore(dependencies)
+	if err != nil {
+		log.Warnf("Failed to restore dependencies from the artifact store, error: %s", err)
+		return dependencies, nil, false
+	}
+	if len(restored) == 0 {
+		log.Printf("No build products available in the artifact store")
+		return dependencies, nil, false
+	}
+
+	log.Donef("Restored from the artifact store: %s", strings.Join(restored, ", "))
+	if len(missing) == 0 {
+		log.Donef("Every dependency is restored, skipping the Carthage command")
+		return nil, restored, true
+	}
+
+	log.Printf("Building only: %s", strings.Join(missing, ", "))
+	return missing, restored, false
+}
+
+// saveArtifacts stores the build products of the built dependencies (every dependency if empty) in the artifact store.
+func (runner Runner) saveArtifacts(dependencies []string) {
+	if runner.artifactCache == nil || !runner.buildsDependencies() {
+		return
+	}
+
+	log.Infof("Storing build products in the artifact store")
+	if err := runner.artifactCache.Save(dependencies); err != nil {
+		log.Warnf("Failed to store build products in the artifact store, error: %s", err)
+	}
+}
+
+// isSplit tells if the bootstrap command is run as a checkout and a build, so the checkouts can be cached separately.
+func (runner Runner) isSplit() bool {
+	return runner.carthageCommand == bootstrapCommand && runner.splitCheckoutAndBuild
+}
+
+// checkout checks out the dependencies, unless the cached checkouts match the Cartfile.resolved.
+func (runner Runner) checkout() error {
+	log.Infof("Check if checkouts are available")
+
+	available, err := runner.cache.IsCheckoutsAvailable()
+	if err != nil {
+		log.Warnf("Failed to check if checkouts are available, error: %s", err)
+	}
+	if available {
+		log.Donef("Checkouts available, reusing the cached checkouts")
+		return nil
+	}
+	log.Warnf("Checkouts not available")
+
+	if err := runner.performCheckout([]string{noBuildArg}, nil); err != nil {
+		return err
+	}
+
+	log.Infof("Creating checkouts indicator")
+	return runner.cache.CreateCheckoutsIndicator()
+}
+
+func (runner Runner) commitCache() error {
+	if runner.isSplit() {
+		return runner.cache.CommitCheckoutsSeparately()
+	}
+	return runner.cache.Commit()
+}
+
+func (runner Runner) commandFailed(err error) error {
+	if runnerErr, ok := err.(*RunnerError); ok {
+		runner.reportXcodebuildLog(runnerErr)
+		if runner.offline {
+			reportNetworkDependencies(runnerErr)
+		}
+		runnerErr.Err = fmt.Errorf("Carthage command failed, error: %s", runnerErr.Err)
+	}
+	return err
+}
+
+// reportXcodebuildLog prints the first compiler errors of the xcodebuild log referenced in the output of the failed command,
+// and copies the log into the deploy dir.
+func (runner Runner) reportXcodebuildLog(runnerErr *RunnerError) {
+	if runnerErr.XcodebuildLogPath == "" {
+		return
+	}
+
+	compilerErrors, err := readCompilerErrors(runnerErr.XcodebuildLogPath)
+	if err != nil {
+		log.Warnf("Failed to read the xcodebuild log (%s), error: %s", runnerErr.XcodebuildLogPath, err)
+		return
+	}
+
+	if len(compilerErrors) > 0 {
+		fmt.Println()
+		if len(compilerErrors) > maxSummarizedCompilerErrors {
+			log.Errorf("xcodebuild failed with %d errors, the first %d:", len(compilerErrors), maxSummarizedCompilerErrors)
+		} else {
+			log.Errorf("xcodebuild failed with %d errors:", len(compilerErrors))
+		}
+
+		var messages []string
+		for i, compilerErr := range compilerErrors {
+			messages = append(messages, compilerErr.message)
+			if i < maxSummarizedCompilerErrors {
+				log.Printf("%s", compilerErr)
+			}
+		}
+
+		// Failures like a Swift version mismatch are reported only in the xcodebuild log
+		if reason, _ := classifyFailure(strings.Join(messages, "\n")); reason != FailureReasonUnknown &&
+			(runnerErr.Reason == FailureReasonUnknown || runnerErr.Reason == FailureReasonCompile) {
+			runnerErr.Reason = reason
+		}
+	}
+
+	if runner.deployDir == "" {
+		return
+	}
+
+	pth := filepath.Join(runner.deployDir, filepath.Base(runnerErr.XcodebuildLogPath))
+	if err := copyFile(runnerErr.XcodebuildLogPath, pth); err != nil {
+		log.Warnf("Failed to copy the xcodebuild log into the deploy dir, error: %s", err)
+		return
+	}
+	log.Printf("The full xcodebuild log is exported: %s", pth)
+}
+
+func (runner Runner) buildsDependencies() bool {
+	return contains([]string{bootstrapCommand, updateCommand, buildCommand}, runner.carthageCommand) && !contains(runner.args, noBuildArg)
+}
+
+// checksOut tells if the Carthage command checks out the dependencies.
+func (runner Runner) checksOut() bool {
+	return contains([]string{bootstrapCommand, updateCommand, checkoutCommand}, runner.carthageCommand) && !contains(runner.args, noCheckoutArg)
+}
+
+// rebuiltDependencies returns the dependencies built by the Run.
+func (runner Runner) rebuiltDependencies(result runResult) []string {
+	if !result.rebuiltAll {
+		return result.rebuiltDependencies
+	}
+
+	names, err := runner.project.resolvedDependencyNames()
+	if err != nil {
+		log.Warnf("Failed to list the rebuilt dependencies, error: %s", err)
+	}
+	return names
+}
+
+// absoluteBuildDir returns the absolute path of the project's Carthage/Build dir.
+func (runner Runner) absoluteBuildDir() string {
+	buildDir, err := filepath.Abs(runner.project.buildDir())
+	if err != nil {
+		return runner.project.buildDir()
+	}
+	return buildDir
+}
+
+func (runner Runner) exportOutputs(result runResult, duration time.Duration, failureReason FailureReason) {
+	if runner.outputExporter == nil {
+		return
+	}
+
+	outputs := []stepOutput{
+		{CacheHitOutputKey, strconv.FormatBool(result.cacheHit)},
+		{RebuiltDependenciesOutputKey, strings.Join(runner.rebuiltDependencies(result), ",")},
+		{BuildDirOutputKey, runner.absoluteBuildDir()},
+		{DurationSecondsOutputKey, strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		{FailureReasonOutputKey, string(failureReason)},
+	}
+
+	if result.outdatedReport != nil {
+		outputs = append(outputs, stepOutput{OutdatedCountOutputKey, strconv.Itoa(len(result.outdatedReport.Dependencies))})
+
+		if runner.deployDir != "" {
+			reportPth, markdownPth, err := runner.exportOutdatedReport(*result.outdatedReport)
+			if err != nil {
+				log.Warnf("Failed to export the outdated report, error: %s", err)
+			} else {
+				outputs = append(outputs, stepOutput{OutdatedReportPathOutputKey, reportPth}, stepOutput{OutdatedMarkdownPathOutputKey, markdownPth})
+			}
 		}
+	}
+
+	if result.resolvedDiff != nil {
+		outputs = append(outputs, stepOutput{ResolvedChangedDependenciesOutputKey, strings.Join(result.resolvedDiff.ChangedDependencies(), ",")})
 
-		if err := runner.cache.Commit(); err != nil {
-			log.Warnf("Cache committing skipped: %s", err)
+		if runner.deployDir != "" {
+			pth, err := runner.exportResolvedDiff(*result.resolvedDiff)
+			if err != nil {
+				log.Warnf("Failed to export the %s diff, error: %s", resolvedFileName, err)
+			} else {
+				outputs = append(outputs, stepOutput{ResolvedDiffPathOutputKey, pth})
+			}
+		}
+	}
+
+	fmt.Println()
+	log.Infof("Exporting outputs")
+	for _, output := range outputs {
+		if err := runner.outputExporter.ExportOutput(output.key, output.value); err != nil {
+			log.Warnf("Failed to export %s, error: %s", output.key, err)
+			continue
+		}
+		log.Printf("- %s: %s", output.key, output.value)
+	}
+}
+
+func (runner Runner) checkRequirements() error {
+	if runner.requirementChecker == nil || runner.requirementCheckMode == RequirementCheckDisabled {
+		return nil
+	}
+
+	log.Infof("Check if %s satisfies the %s requirements", resolvedFileName, cartfileName)
+
+	results, err := runner.requirementChecker.Check()
+	if err != nil {
+		if runner.requirementCheckMode == RequirementCheckFail {
+			return fmt.Errorf("failed to check requirements, error: %s", err)
 		}
+		log.Warnf("Failed to check requirements, error: %s", err)
+		return nil
+	}
+
+	var violated []string
+	for _, result := range results {
+		switch result.Status {
+		case cartfile.CheckSatisfied:
+			log.Printf("- %s", result)
+		case cartfile.CheckUnverified:
+			log.Warnf("- %s", result)
+		case cartfile.CheckViolated:
+			log.Errorf("- %s", result)
+			violated = append(violated, result.Requirement.Name())
+		}
+	}
+
+	if len(violated) == 0 {
+		log.Donef("%s satisfies the requirements", resolvedFileName)
+		return nil
 	}
 
+	message := fmt.Sprintf("%s does not satisfy the requirements of: %s, run `carthage update` and commit the updated %s",
+		resolvedFileName, strings.Join(violated, ", "), resolvedFileName)
+	if runner.requirementCheckMode == RequirementCheckFail {
+		return errors.New(message)
+	}
+	log.Warnf("%s", message)
+
 	return nil
 }
 
@@ -115,49 +558,176 @@ func (runner Runner) isCacheAvailable() bool {
 	return cacheAvailable
 }
 
-func (runner Runner) perform() error {
-	var function = runner.executeCommand
+func (runner Runner) outdatedDependencies() []string {
+	log.Infof("Check if cache is partially available")
 
-	if contains(getRetryableCommands(), runner.carthageCommand) {
-		function = func() error {
-			return retry.Times(1).Wait(3 * time.Second).TryWithAbort(func(attempt uint) (error, bool) {
-				if attempt > 0 {
-					log.Warnf("Carthage %s (possible) network failure, retrying ...", runner.carthageCommand)
-				}
+	dependencies, err := runner.cache.OutdatedDependencies()
+	if err != nil {
+		log.Warnf("Failed to check which dependencies are outdated, error: %s", err)
+		return nil
+	}
 
-				err := runner.executeCommand()
+	if len(dependencies) == 0 {
+		log.Printf("Cached dependencies can not be reused, building all dependencies")
+		return nil
+	}
 
-				return err, !hasRetryableFailure(err)
-			})
-		}
+	log.Donef("Reusing cached dependencies, rebuilding only: %s", strings.Join(dependencies, ", "))
+	return dependencies
+}
+
+func (runner Runner) perform(options, dependencies []string) error {
+	// Network failures are expected in offline mode
+	if !contains(getRetryableCommands(), runner.carthageCommand) || runner.offline {
+		return runner.executeCommand(options, dependencies)
 	}
 
-	return function()
+	for retry := uint(1); ; retry++ {
+		err := runner.executeCommand(options, dependencies)
+		if err == nil {
+			return nil
+		}
+
+		failure, retryable := classifyRetryableFailure(err, time.Now())
+		if !retryable {
+			return err
+		}
+
+		wait, ok := runner.retryPolicy.wait(retry, failure)
+		if !ok {
+			if retry <= runner.retryPolicy.Count {
+				log.Warnf("Carthage %s failed (%s), not retrying, the rate limit resets in %s", runner.carthageCommand, failure.reason, wait)
+			}
+			return err
+		}
+
+		log.Warnf("Carthage %s failed (%s), retrying in %s (%d/%d) ...", runner.carthageCommand, failure.reason, wait, retry, runner.retryPolicy.Count)
+		runner.sleep(wait)
+	}
 }
 
-func (runner Runner) executeCommand() error {
-	log.Infof("Running Carthage command")
+func (runner Runner) sleep(duration time.Duration) {
+	if runner.sleepFunc != nil {
+		runner.sleepFunc(duration)
+		return
+	}
+	time.Sleep(duration)
+}
 
+// newCommand builds the Carthage command with the options and dependencies appended to the configured arguments.
+func (runner Runner) newCommand(options, dependencies []string) *command.Model {
 	builder := runner.commandBuilder.
 		AddGitHubToken(runner.githubAccessToken).
 		AddXCConfigFile(runner.xcconfigPath).
 		Append(runner.carthageCommand).
 		Append(runner.args...)
-	var stderrBuf bytes.Buffer
+	if runner.offline {
+		builder = builder.AddEnvs(offlineEnvs...)
+	}
+	if len(options) > 0 {
+		builder = builder.Append(options...)
+	}
+	if len(dependencies) > 0 {
+		builder = builder.Append(dependencies...)
+	}
+	return builder.Command()
+}
+
+func (runner Runner) executeCommand(options, dependencies []string) error {
+	_, err := runner.runCommand(options, dependencies)
+	return err
+}
+
+// runCommand runs the Carthage command, and returns its output.
+func (runner Runner) runCommand(options, dependencies []string) (string, error) {
+	log.Infof("Running Carthage command")
+
+	outputTail, stderrTail := newTailBuffer(commandOutputTailSize), newTailBuffer(commandOutputTailSize)
+	output := &lockedWriter{writer: outputTail}
 
-	cmd := builder.Command()
-	cmd.SetStdout(os.Stdout)
-	cmd.SetStderr(io.MultiWriter(os.Stderr, &stderrBuf))
+	stdout, stderr, flush := runner.commandOutputs()
 
-	log.Donef("$ %s", cmd.PrintableCommandArgs())
+	cmd := runner.newCommand(options, dependencies)
+	cmd.SetStdout(io.MultiWriter(stdout, output))
+	cmd.SetStderr(io.MultiWriter(stderr, output, stderrTail))
+
+	log.Donef("%s$ %s", runner.outputPrefix, cmd.PrintableCommandArgs())
 
 	err := cmd.Run()
+	flush()
 
 	if err == nil {
-		return nil
+		return outputTail.String(), nil
+	}
+
+	return outputTail.String(), newRunnerError(stderrTail.String(), outputTail.String(), err)
+}
+
+// commandOutputs returns the writers of the stdout and stderr of a Carthage command, and a func writing their last lines.
+// The lines are prefixed with the outputPrefix, if the projects run in parallel.
+func (runner Runner) commandOutputs() (io.Writer, io.Writer, func()) {
+	if runner.outputPrefix == "" {
+		return os.Stdout, os.Stderr, func() {}
+	}
+
+	stdout := &prefixWriter{prefix: runner.outputPrefix, writer: os.Stdout, lock: runner.outputLock}
+	stderr := &prefixWriter{prefix: runner.outputPrefix, writer: os.Stderr, lock: runner.outputLock}
+	return stdout, stderr, func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// lockedWriter serializes the writes of a command's stdout and stderr, which are copied from separate goroutines.
+type lockedWriter struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+// Write ...
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.writer.Write(p)
+}
+
+// tailBuffer keeps the last lines written into it, at most size bytes of them.
+// The failure of a Carthage command is told by the end of its output, so the output of a long build is not kept in memory.
+type tailBuffer struct {
+	size int
+	buf  []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+// Write ...
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	// The buffer is trimmed only after it doubled, so the kept bytes are not moved on every write.
+	if len(b.buf) > 2*b.size {
+		b.buf = append(b.buf[:0], b.tail()...)
 	}
+	return len(p), nil
+}
+
+// String returns the last lines written, the first line is dropped if only its end fits.
+func (b *tailBuffer) String() string {
+	return string(b.tail())
+}
 
-	return &RunnerError{stderrBuf.String(), err}
+func (b *tailBuffer) tail() []byte {
+	if len(b.buf) <= b.size {
+		return b.buf
+	}
+
+	tail := b.buf[len(b.buf)-b.size:]
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return tail
 }
 
 func contains(slice []string, value string) bool {
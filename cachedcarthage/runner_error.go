@@ -2,13 +2,62 @@ package cachedcarthage
 
 import (
 	"errors"
-	"strings"
+	"regexp"
+	"strconv"
+	"time"
 )
 
+// FailureReason is the category of a Carthage command failure, detected from the command output.
+type FailureReason string
+
+// Failure reasons.
+const (
+	FailureReasonUnknown              FailureReason = "unknown"
+	FailureReasonNetwork              FailureReason = "network"
+	FailureReasonRateLimit            FailureReason = "rate_limit"
+	FailureReasonResolutionConflict   FailureReason = "dependency_resolution_conflict"
+	FailureReasonCompile              FailureReason = "compile_failure"
+	FailureReasonMissingScheme        FailureReason = "missing_scheme"
+	FailureReasonCodeSigning          FailureReason = "code_signing"
+	FailureReasonSwiftVersionMismatch FailureReason = "swift_version_mismatch"
+	FailureReasonNetworkRequired      FailureReason = "network_required"
+)
+
+var failureRemediations = map[FailureReason]string{
+	FailureReasonNetwork: "A network error occurred while fetching the dependencies. " +
+		"These are usually temporary: re-run the build, or increase the `retry_count` input.",
+	FailureReasonRateLimit: "The GitHub API rate limit is exceeded. " +
+		"Set the `github_access_token` input to a Personal Access Token to get a higher rate limit.",
+	FailureReasonResolutionConflict: "Carthage could not pick versions satisfying every requirement. " +
+		"Check the requirements of the Cartfile and Cartfile.private, run `carthage update` locally and commit the updated Cartfile.resolved.",
+	FailureReasonCompile: "A dependency failed to compile. Check the xcodebuild log referenced in the output. " +
+		"Dependencies often fail to compile with a newer Xcode: update the dependency, or select the Xcode stack it supports.",
+	FailureReasonMissingScheme: "A dependency has no shared framework scheme for the platform. " +
+		"Carthage builds only shared schemes: share the framework scheme in the dependency's Xcode project (Manage Schemes > Shared), " +
+		"use a version of the dependency which has one, or exclude the platform with the `--platform` option.",
+	FailureReasonCodeSigning: "Code signing failed while building a dependency. Frameworks built by Carthage do not need to be signed: " +
+		"use the `xcconfig` input to disable signing, for example with `CODE_SIGNING_REQUIRED = NO` and `CODE_SIGNING_ALLOWED = NO`.",
+	FailureReasonSwiftVersionMismatch: "A prebuilt framework was compiled with a different Swift version than the selected Xcode. " +
+		"Use the `--no-use-binaries` option to build the dependencies from source, or select the Xcode stack the framework was built with.",
+	FailureReasonNetworkRequired: "The step runs in offline mode, so Carthage can not fetch the dependencies. " +
+		"Make sure the cache of the same Cartfile.resolved and build settings is restored before the step, or disable the `offline` input.",
+}
+
+// Remediation returns how to fix the failure, empty for unknown failures.
+func (reason FailureReason) Remediation() string {
+	return failureRemediations[reason]
+}
+
 // RunnerError ...
 type RunnerError struct {
+	// Output is the tail of the stderr of the failed command, the failure is categorized by it.
 	Output string
-	Err error
+	// CombinedOutput is the tail of the stdout and stderr of the failed command, in the order they were written.
+	CombinedOutput string
+	Err            error
+	Reason         FailureReason
+	// XcodebuildLogPath is the xcodebuild log referenced in the output, if the failed command built any dependency.
+	XcodebuildLogPath string
 }
 
 // Error ...
@@ -16,26 +65,100 @@ func (e *RunnerError) Error() string {
 	return e.Err.Error()
 }
 
-func getRetryableCommands() []string {
-	return []string{bootstrapCommand, updateCommand}
+// newRunnerError creates a RunnerError, categorized by the stderr of the failed command.
+func newRunnerError(stderr, output string, err error) *RunnerError {
+	reason, _ := classifyFailure(stderr)
+	return &RunnerError{
+		Output:            stderr,
+		CombinedOutput:    output,
+		Err:               err,
+		Reason:            reason,
+		XcodebuildLogPath: parseXcodebuildLogPath(output),
+	}
 }
 
-func getErrorSlices() []string {
-	return []string{"failed to connect to", "timed out"}
+// failurePatterns are matched in order, so the patterns of failures which are reported together with
+// more general ones (like a code signing failure with the build failure) come first.
+// A compile failure comes before the network failures, as the compiler output may contain network related words (like "timed out").
+var failurePatterns = []struct {
+	reason      FailureReason
+	description string
+	pattern     *regexp.Regexp
+}{
+	{FailureReasonRateLimit, "GitHub rate limit exceeded", regexp.MustCompile(`(?i)rate limit exceeded|x-ratelimit-remaining:\s*0\b`)},
+	{FailureReasonSwiftVersionMismatch, "Swift version mismatch", regexp.MustCompile(`(?i)module compiled with swift \S+ cannot be imported|compiled with a (newer|older) version of the swift language|incompatible swift version`)},
+	{FailureReasonCodeSigning, "code signing failure", regexp.MustCompile(`(?i)code ?sign(ing)? error|requires a development team|no signing certificate|no profiles? for .* (was|were) found|error: [^\n]*provisioning profile`)},
+	{FailureReasonMissingScheme, "missing shared scheme", regexp.MustCompile(`(?i)has no shared framework schemes|does not contain a scheme named|scheme .* is not currently configured`)},
+	{FailureReasonResolutionConflict, "dependency resolution conflict", regexp.MustCompile(`(?i)could not pick a version for|no available version for|mutually incompatible requirements|dependency graph conflict|unsatisfiable`)},
+	{FailureReasonCompile, "compile failure", regexp.MustCompile(`(?i)\*\* build failed \*\*|task failed with exit code 65|failed to compile`)},
+	{FailureReasonNetwork, "HTTP server error", regexp.MustCompile(`(?i)(returned error|http(/[\d.]+)?|status code|response code)\W{0,3}5\d\d\b`)},
+	{FailureReasonNetwork, "host could not be resolved", regexp.MustCompile(`(?i)could not resolve host`)},
+	{FailureReasonNetwork, "TLS handshake failure", regexp.MustCompile(`(?i)(tls|ssl)\S* ?handshake|ssl_connect|ssl_error_syscall`)},
+	{FailureReasonNetwork, "git fetch ended early", regexp.MustCompile(`(?i)early eof|the remote end hung up unexpectedly|unexpected disconnect while reading sideband packet`)},
+	{FailureReasonNetwork, "connection failure", regexp.MustCompile(`(?i)failed to connect to`)},
+	{FailureReasonNetwork, "timeout", regexp.MustCompile(`(?i)timed out`)},
 }
 
-func hasRetryableFailure(err error) bool {
+// classifyFailure returns the reason and a short description of the failure the output reports.
+func classifyFailure(output string) (FailureReason, string) {
+	for _, failurePattern := range failurePatterns {
+		if failurePattern.pattern.MatchString(output) {
+			return failurePattern.reason, failurePattern.description
+		}
+	}
+	return FailureReasonUnknown, ""
+}
+
+// retryableFailure is a (possibly) temporary failure of a Carthage command.
+type retryableFailure struct {
+	reason string
+	// retryAfter is the time left until the GitHub rate limit resets, if the output tells it.
+	retryAfter time.Duration
+}
+
+var (
+	rateLimitResetPattern = regexp.MustCompile(`(?i)x-ratelimit-reset:\s*(\d+)`)
+	retryAfterPattern     = regexp.MustCompile(`(?i)retry-after:\s*(\d+)`)
+)
+
+func getRetryableCommands() []string {
+	return []string{bootstrapCommand, updateCommand}
+}
+
+// classifyRetryableFailure returns the retryable failure found in the output of the failed command, and false if the failure is not retryable.
+func classifyRetryableFailure(err error, now time.Time) (retryableFailure, bool) {
 	var runnerError *RunnerError
+	if !errors.As(err, &runnerError) {
+		return retryableFailure{}, false
+	}
+
+	reason, description := classifyFailure(runnerError.Output)
+	if reason != FailureReasonNetwork && reason != FailureReasonRateLimit {
+		return retryableFailure{}, false
+	}
 
-	if errors.As(err, &runnerError) {
-		output := strings.ToLower(runnerError.Output)
+	return retryableFailure{
+		reason:     description,
+		retryAfter: parseRetryAfter(runnerError.Output, now),
+	}, true
+}
 
-		for _, str := range getErrorSlices(){
-			if strings.Contains(output, str) {
-				return true
+// parseRetryAfter parses the time left until the rate limit resets from the X-RateLimit-Reset (epoch seconds)
+// or Retry-After (seconds) response headers printed in the output.
+func parseRetryAfter(output string, now time.Time) time.Duration {
+	if match := rateLimitResetPattern.FindStringSubmatch(output); match != nil {
+		if reset, err := strconv.ParseInt(match[1], 10, 64); err == nil {
+			if retryAfter := time.Unix(reset, 0).Sub(now); retryAfter > 0 {
+				return retryAfter
 			}
 		}
 	}
 
-	return false
+	if match := retryAfterPattern.FindStringSubmatch(output); match != nil {
+		if seconds, err := strconv.Atoi(match[1]); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return 0
 }
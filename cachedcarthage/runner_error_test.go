@@ -0,0 +1,127 @@
+package cachedcarthage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// classifyFailure
+func Test_GivenCommandOutput_WhenClassifyFailureCalled_ThenExpectCorrectFailureReason(t *testing.T) {
+	testScenarios := []struct {
+		output   string
+		expected FailureReason
+	}{
+		{"API rate limit exceeded for 1.2.3.4.", FailureReasonRateLimit},
+		{"fatal: unable to access 'https://github.com/Alamofire/Alamofire.git/': Could not resolve host: github.com", FailureReasonNetwork},
+		{"Could not pick a version for github \"Alamofire/Alamofire\", due to mutually incompatible requirements", FailureReasonResolutionConflict},
+		{"No available version for github \"Moya/Moya\" satisfies the requirement: ~> 16.0", FailureReasonResolutionConflict},
+		{"Build Failed\n\tTask failed with exit code 65:\n\t/usr/bin/xcrun xcodebuild -workspace ...", FailureReasonCompile},
+		{"Dependency \"Nimble\" has no shared framework schemes for any of the platforms: iOS", FailureReasonMissingScheme},
+		{"error: Signing for \"Alamofire iOS\" requires a development team.\n** BUILD FAILED **", FailureReasonCodeSigning},
+		{"Code Signing Error: No profiles for 'org.alamofire.Alamofire' were found", FailureReasonCodeSigning},
+		{"error: Module compiled with Swift 5.3 cannot be imported by the Swift 5.5 compiler\n** BUILD FAILED **", FailureReasonSwiftVersionMismatch},
+		{"Incompatible Swift version - framework was built with 5.3 and the local version is 5.5", FailureReasonSwiftVersionMismatch},
+		{"error: No provisioning profile found for \"Alamofire iOS\"\n** BUILD FAILED **", FailureReasonCodeSigning},
+		{"note: Using the provisioning profile of the team\n** BUILD FAILED **", FailureReasonCompile},
+		{"error: Connection timed out while loading the module\n** BUILD FAILED **", FailureReasonCompile},
+		{"Segmentation fault: 11", FailureReasonUnknown},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		actual, _ := classifyFailure(scenario.output)
+
+		// Then
+		assert.Equal(t, scenario.expected, actual, scenario.output)
+	}
+}
+
+func Test_GivenKnownFailureReasons_WhenRemediationCalled_ThenExpectRemediation(t *testing.T) {
+	testScenarios := []FailureReason{
+		FailureReasonNetwork,
+		FailureReasonRateLimit,
+		FailureReasonResolutionConflict,
+		FailureReasonCompile,
+		FailureReasonMissingScheme,
+		FailureReasonCodeSigning,
+		FailureReasonSwiftVersionMismatch,
+	}
+
+	for _, reason := range testScenarios {
+		// Then
+		assert.NotEmpty(t, reason.Remediation(), reason)
+	}
+	assert.Empty(t, FailureReasonUnknown.Remediation())
+}
+
+// classifyRetryableFailure
+func Test_GivenCommandOutput_WhenClassifyRetryableFailureCalled_ThenExpectCorrectReason(t *testing.T) {
+	testScenarios := []struct {
+		output   string
+		expected string
+	}{
+		{"fatal: unable to access 'https://github.com/Alamofire/Alamofire.git/': Failed to connect to github.com port 443: Operation timed out", "connection failure"},
+		{"fatal: unable to access 'https://github.com/Alamofire/Alamofire.git/': Could not resolve host: github.com", "host could not be resolved"},
+		{"fatal: unable to access 'https://github.com/Alamofire/Alamofire.git/': The requested URL returned error: 502", "HTTP server error"},
+		{"< HTTP/2 503", "HTTP server error"},
+		{"fatal: unable to access 'https://github.com/Alamofire/Alamofire.git/': gnutls_handshake() failed: Error in the pull function.", "TLS handshake failure"},
+		{"LibreSSL SSL_connect: SSL_ERROR_SYSCALL in connection to github.com:443", "TLS handshake failure"},
+		{"fatal: early EOF\nfatal: fetch-pack: invalid index-pack output", "git fetch ended early"},
+		{"API rate limit exceeded for 1.2.3.4. (But here's the good news: Authenticated requests get a higher rate limit.)", "GitHub rate limit exceeded"},
+		{"Operation timed out", "timeout"},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		failure, retryable := classifyRetryableFailure(&RunnerError{Output: scenario.output, Err: errors.New("exit status 1")}, time.Now())
+
+		// Then
+		assert.True(t, retryable, scenario.output)
+		assert.Equal(t, scenario.expected, failure.reason, scenario.output)
+	}
+}
+
+func Test_GivenNotRetryableOutput_WhenClassifyRetryableFailureCalled_ThenExpectNotRetryable(t *testing.T) {
+	testScenarios := []error{
+		&RunnerError{Output: "Dependency graph conflict", Err: errors.New("exit status 1")},
+		&RunnerError{Output: "The requested URL returned error: 404", Err: errors.New("exit status 1")},
+		&RunnerError{Output: "Task failed with exit code 65", Err: errors.New("exit status 1")},
+		errors.New("timed out"),
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		_, retryable := classifyRetryableFailure(scenario, time.Now())
+
+		// Then
+		assert.False(t, retryable, scenario.Error())
+	}
+}
+
+func Test_GivenRateLimitResetInOutput_WhenClassifyRetryableFailureCalled_ThenExpectRetryAfter(t *testing.T) {
+	// Given
+	now := time.Unix(1634567000, 0)
+	output := "API rate limit exceeded\nX-RateLimit-Remaining: 0\nX-RateLimit-Reset: 1634567090"
+
+	// When
+	failure, retryable := classifyRetryableFailure(&RunnerError{Output: output, Err: errors.New("exit status 1")}, now)
+
+	// Then
+	assert.True(t, retryable)
+	assert.Equal(t, 90*time.Second, failure.retryAfter)
+}
+
+func Test_GivenPastRateLimitReset_WhenClassifyRetryableFailureCalled_ThenExpectNoRetryAfter(t *testing.T) {
+	// Given
+	now := time.Unix(1634567000, 0)
+	output := "API rate limit exceeded\nX-RateLimit-Reset: 1634566000"
+
+	// When
+	failure, _ := classifyRetryableFailure(&RunnerError{Output: output, Err: errors.New("exit status 1")}, now)
+
+	// Then
+	assert.Equal(t, time.Duration(0), failure.retryAfter)
+}
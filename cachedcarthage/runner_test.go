@@ -2,11 +2,15 @@ package cachedcarthage
 
 import (
 	"errors"
-	"github.com/bitrise-io/go-utils/command"
-	"github.com/stretchr/testify/mock"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-steplib/steps-carthage/cartfile"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // The first part writes the given string to stderr and the second part provides the exit code 1.
@@ -15,6 +19,43 @@ const (
 	failingCommandWithFailedToConnectToStderr = "echo failed to connect to 1>&2 && false"
 )
 
+// NewRunner
+func Test_GivenOptions_WhenNewRunnerCalled_ThenExpectOptionalBehavioursSet(t *testing.T) {
+	// Given
+	artifactCache := givenMockDependencyArtifactCache()
+	options := RunnerOptions{
+		ArtifactCache:         artifactCache,
+		RetryPolicy:           NewRetryPolicy(2, time.Second, 2),
+		SplitCheckoutAndBuild: true,
+		ParallelBuild:         NewParallelBuild(4, true, nil),
+		DriftGuard:            NewDriftGuard(ResolvedDriftFail, nil, nil),
+		Offline:               true,
+	}
+
+	// When
+	runner := NewRunner(bootstrapCommand, nil, "", "", nil, nil, nil, RequirementCheckWarn, Project{}, "", nil, options)
+
+	// Then
+	assert.Equal(t, artifactCache, runner.artifactCache)
+	assert.Equal(t, options.RetryPolicy, runner.retryPolicy)
+	assert.True(t, runner.isSplit())
+	assert.Equal(t, options.ParallelBuild, runner.parallelBuild)
+	assert.Equal(t, options.DriftGuard, runner.driftGuard)
+	assert.True(t, runner.offline)
+}
+
+func Test_GivenNoOptions_WhenNewRunnerCalled_ThenExpectOptionalBehavioursDisabled(t *testing.T) {
+	// When
+	runner := NewRunner(bootstrapCommand, nil, "", "", nil, nil, nil, RequirementCheckWarn, Project{}, "", nil, RunnerOptions{})
+
+	// Then
+	assert.Nil(t, runner.artifactCache)
+	assert.Zero(t, runner.retryPolicy.Count)
+	assert.False(t, runner.isSplit())
+	assert.Equal(t, ResolvedDriftMode(""), runner.driftGuard.Mode)
+	assert.False(t, runner.offline)
+}
+
 // Run
 func Test_GivenNotBootstrapCommand_WhenRunCalled_ThenExpectNoErrorAndCacheNotCreated(t *testing.T) {
 	// Given
@@ -41,6 +82,7 @@ func Test_GivenBootstrapCommandAndCacheNotAvailableAndCacheCreateFails_WhenRunCa
 	expectedError := errors.New("sad error")
 	mockCarthageCache := givenMockCarthageCache().
 		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
 		GivenCreateIndicatorFails(expectedError)
 	runner := Runner{
 		carthageCommand: "bootstrap",
@@ -59,6 +101,7 @@ func Test_GivenBootstrapCommandAndCacheNotAvailableAndCacheCreateSucceeds_WhenRu
 	// Given
 	mockCarthageCache := givenMockCarthageCache().
 		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
 		GivenCreateIndicatorSucceeds().
 		GivenCommitSucceeds()
 	runner := Runner{
@@ -122,6 +165,541 @@ func Test_GivenBootstrapCommandAndCacheAvailableAndCollectSucceeds_WhenRunCalled
 	mockCarthageCache.AssertNumberOfCalls(t, "Commit", 1)
 }
 
+func Test_GivenBootstrapCommandAndCachePartiallyAvailable_WhenRunCalled_ThenExpectOutdatedDependenciesBuilt(t *testing.T) {
+	// Given
+	outdatedDependencies := []string{"Alamofire", "Moya"}
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(outdatedDependencies).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertCalled(t, "Append", outdatedDependencies)
+	mockCarthageCache.AssertCalled(t, "CreateIndicator")
+}
+
+func Test_GivenBootstrapCommandAndOutdatedDependenciesFails_WhenRunCalled_ThenExpectAllDependenciesBuilt(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesFails(errors.New("sad error")).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		args:            []string{"--platform", "ios"},
+		cache:           mockCarthageCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertNumberOfCalls(t, "Append", 2)
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"bootstrap"})
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"--platform", "ios"})
+}
+
+func Test_GivenSplitBootstrapAndCheckoutsNotAvailable_WhenRunCalled_ThenExpectCheckoutAndBuildRun(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
+		GivenIsCheckoutsAvailableSucceeds(false).
+		GivenCreateCheckoutsIndicatorSucceeds().
+		GivenCreateIndicatorSucceeds().
+		GivenCommitCheckoutsSeparatelySucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilderReturnsCommands([]*command.Model{
+		command.New("echo", "checkout"),
+		command.New("echo", "build"),
+	})
+	runner := Runner{
+		carthageCommand:       "bootstrap",
+		args:                  []string{"--platform", "ios"},
+		cache:                 mockCarthageCache,
+		commandBuilder:        mockCommandBuilder,
+		splitCheckoutAndBuild: true,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"--no-build"})
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"--no-checkout"})
+	mockCarthageCache.AssertCalled(t, "CreateCheckoutsIndicator")
+	mockCarthageCache.AssertCalled(t, "CommitCheckoutsSeparately")
+	mockCarthageCache.AssertNotCalled(t, "Commit")
+}
+
+func Test_GivenSplitBootstrapAndCheckoutsAvailable_WhenRunCalled_ThenExpectOnlyOutdatedDependenciesBuilt(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds([]string{"Alamofire"}).
+		GivenIsCheckoutsAvailableSucceeds(true).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitCheckoutsSeparatelySucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand:       "bootstrap",
+		cache:                 mockCarthageCache,
+		commandBuilder:        mockCommandBuilder,
+		splitCheckoutAndBuild: true,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertNotCalled(t, "Append", []string{"--no-build"})
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"--no-checkout"})
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"Alamofire"})
+	mockCarthageCache.AssertNotCalled(t, "CreateCheckoutsIndicator")
+}
+
+func Test_GivenSplitBootstrapAndCacheAvailable_WhenRunCalled_ThenExpectCheckoutsCommittedSeparately(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(true).
+		GivenCommitCheckoutsSeparatelySucceeds()
+	runner := Runner{
+		carthageCommand:       "bootstrap",
+		cache:                 mockCarthageCache,
+		commandBuilder:        givenStubbedCommandBuilderReturnFailingCommand(),
+		splitCheckoutAndBuild: true,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCarthageCache.AssertCalled(t, "CommitCheckoutsSeparately")
+	mockCarthageCache.AssertNotCalled(t, "IsCheckoutsAvailable")
+}
+
+func Test_GivenBootstrapCommandAndEveryDependencyInArtifactStore_WhenRunCalled_ThenExpectCommandNotExecutedAndCacheCreated(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockArtifactCache := givenMockDependencyArtifactCache().
+		GivenRestoreSucceeds([]string{"Alamofire", "Moya"}, nil)
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		artifactCache:   mockArtifactCache,
+		commandBuilder:  givenStubbedCommandBuilderReturnFailingCommand(),
+	}
+
+	// When
+	result, err := runner.run()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, runResult{restoredDependencies: []string{"Alamofire", "Moya"}}, result)
+	mockArtifactCache.AssertCalled(t, "Restore", []string(nil))
+	mockArtifactCache.AssertNotCalled(t, "Save", mock.Anything)
+	mockCarthageCache.AssertCalled(t, "CreateIndicator")
+	mockCarthageCache.AssertCalled(t, "Commit")
+}
+
+func Test_GivenBootstrapCommandAndSomeDependenciesInArtifactStore_WhenRunCalled_ThenExpectMissingDependenciesBuiltAndSaved(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds([]string{"Alamofire", "Moya"}).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockArtifactCache := givenMockDependencyArtifactCache().
+		GivenRestoreSucceeds([]string{"Alamofire"}, []string{"Moya"}).
+		GivenSaveSucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		artifactCache:   mockArtifactCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	result, err := runner.run()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, runResult{restoredDependencies: []string{"Alamofire"}, rebuiltDependencies: []string{"Moya"}}, result)
+	mockArtifactCache.AssertCalled(t, "Restore", []string{"Alamofire", "Moya"})
+	mockCommandBuilder.AssertCalled(t, "Append", []string{"Moya"})
+	mockArtifactCache.AssertCalled(t, "Save", []string{"Moya"})
+}
+
+func Test_GivenBootstrapCommandAndArtifactStoreRestoreFails_WhenRunCalled_ThenExpectAllDependenciesBuiltAndSaved(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockArtifactCache := givenMockDependencyArtifactCache().
+		GivenRestoreFails(errors.New("sad error")).
+		GivenSaveSucceeds()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           mockCarthageCache,
+		artifactCache:   mockArtifactCache,
+		commandBuilder:  mockCommandBuilder,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCommandBuilder.AssertNumberOfCalls(t, "Append", 2)
+	mockArtifactCache.AssertCalled(t, "Save", []string(nil))
+}
+
+func Test_GivenBootstrapCommandWithNoBuild_WhenRunCalled_ThenExpectArtifactStoreNotUsed(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
+		GivenCreateIndicatorSucceeds().
+		GivenCommitSucceeds()
+	mockArtifactCache := givenMockDependencyArtifactCache()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		args:            []string{"--no-build"},
+		cache:           mockCarthageCache,
+		artifactCache:   mockArtifactCache,
+		commandBuilder:  givenStubbedCommandBuilder(),
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockArtifactCache.AssertNotCalled(t, "Restore", mock.Anything)
+	mockArtifactCache.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func Test_GivenBootstrapCommandAndRequirementsViolatedInFailMode_WhenRunCalled_ThenExpectErrorAndCommandNotExecuted(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache()
+	mockCommandBuilder := givenStubbedCommandBuilder()
+	runner := Runner{
+		carthageCommand:      "bootstrap",
+		cache:                mockCarthageCache,
+		commandBuilder:       mockCommandBuilder,
+		requirementChecker:   givenMockRequirementChecker().GivenCheckSucceeds(givenViolatedCheckResults()),
+		requirementCheckMode: RequirementCheckFail,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.EqualError(t, err, "Cartfile.resolved does not satisfy the requirements of: Alamofire, run `carthage update` and commit the updated Cartfile.resolved")
+	mockCarthageCache.AssertNotCalled(t, "IsAvailable")
+	mockCommandBuilder.AssertNotCalled(t, "Command")
+}
+
+func Test_GivenBootstrapCommandAndRequirementsViolatedInWarnMode_WhenRunCalled_ThenExpectNoError(t *testing.T) {
+	// Given
+	mockCarthageCache := givenMockCarthageCache().
+		GivenIsAvailableSucceeds(true).
+		GivenCommitSucceeds()
+	runner := Runner{
+		carthageCommand:      "bootstrap",
+		cache:                mockCarthageCache,
+		commandBuilder:       givenStubbedCommandBuilder(),
+		requirementChecker:   givenMockRequirementChecker().GivenCheckSucceeds(givenViolatedCheckResults()),
+		requirementCheckMode: RequirementCheckWarn,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockCarthageCache.AssertCalled(t, "IsAvailable")
+}
+
+func Test_GivenRequirementCheckFailsInFailMode_WhenCheckRequirementsCalled_ThenExpectError(t *testing.T) {
+	// Given
+	runner := Runner{
+		requirementChecker:   givenMockRequirementChecker().GivenCheckFails(errors.New("sad error")),
+		requirementCheckMode: RequirementCheckFail,
+	}
+
+	// When
+	err := runner.checkRequirements()
+
+	// Then
+	assert.EqualError(t, err, "failed to check requirements, error: sad error")
+}
+
+func Test_GivenRequirementCheckDisabled_WhenCheckRequirementsCalled_ThenExpectCheckerNotCalled(t *testing.T) {
+	// Given
+	mockRequirementChecker := givenMockRequirementChecker()
+	runner := Runner{
+		requirementChecker:   mockRequirementChecker,
+		requirementCheckMode: RequirementCheckDisabled,
+	}
+
+	// When
+	err := runner.checkRequirements()
+
+	// Then
+	assert.NoError(t, err)
+	mockRequirementChecker.AssertNotCalled(t, "Check")
+}
+
+// Outputs
+func Test_GivenBootstrapCommandAndCacheAvailable_WhenRunCalled_ThenExpectCacheHitOutputs(t *testing.T) {
+	// Given
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache:           givenMockCarthageCache().GivenIsAvailableSucceeds(true).GivenCommitSucceeds(),
+		commandBuilder:  givenStubbedCommandBuilderReturnFailingCommand(),
+		project:         NewProject("/base/dir"),
+		outputExporter:  mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", CacheHitOutputKey, "true")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", RebuiltDependenciesOutputKey, "")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", BuildDirOutputKey, "/base/dir/Carthage/Build")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", DurationSecondsOutputKey, "0")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", FailureReasonOutputKey, "")
+}
+
+func Test_GivenBootstrapCommandAndCachePartiallyAvailable_WhenRunCalled_ThenExpectOutdatedDependenciesExported(t *testing.T) {
+	// Given
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache: givenMockCarthageCache().
+			GivenIsAvailableSucceeds(false).
+			GivenOutdatedDependenciesSucceeds([]string{"Alamofire", "Moya"}).
+			GivenCreateIndicatorSucceeds().
+			GivenCommitSucceeds(),
+		commandBuilder: givenStubbedCommandBuilder(),
+		outputExporter: mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", CacheHitOutputKey, "false")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", RebuiltDependenciesOutputKey, "Alamofire,Moya")
+}
+
+func Test_GivenBootstrapCommandAndCacheNotAvailable_WhenRunCalled_ThenExpectAllResolvedDependenciesExported(t *testing.T) {
+	// Given
+	projectDir := givenTempDir(t)
+	givenFile(t, filepath.Join(projectDir, resolvedFileName), `github "Alamofire/Alamofire" "5.4.1"
+github "Moya/Moya" "14.0.0"
+`)
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "bootstrap",
+		cache: givenMockCarthageCache().
+			GivenIsAvailableSucceeds(false).
+			GivenOutdatedDependenciesSucceeds(nil).
+			GivenCreateIndicatorSucceeds().
+			GivenCommitSucceeds(),
+		commandBuilder: givenStubbedCommandBuilder(),
+		project:        NewProject(projectDir),
+		outputExporter: mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", CacheHitOutputKey, "false")
+	mockOutputExporter.AssertCalled(t, "ExportOutput", RebuiltDependenciesOutputKey, "Alamofire,Moya")
+}
+
+func Test_GivenNotBuildingCommand_WhenRunCalled_ThenExpectNoRebuiltDependenciesExported(t *testing.T) {
+	// Given
+	projectDir := givenTempDir(t)
+	givenFile(t, filepath.Join(projectDir, resolvedFileName), `github "Alamofire/Alamofire" "5.4.1"`)
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand: "checkout",
+		cache:           givenMockCarthageCache(),
+		commandBuilder:  givenStubbedCommandBuilder(),
+		project:         NewProject(projectDir),
+		outputExporter:  mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", RebuiltDependenciesOutputKey, "")
+}
+
+func Test_GivenExportOutputFails_WhenRunCalled_ThenExpectNoError(t *testing.T) {
+	// Given
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputFails(errors.New("sad error"))
+	runner := Runner{
+		carthageCommand: "version",
+		cache:           givenMockCarthageCache(),
+		commandBuilder:  givenStubbedCommandBuilder(),
+		outputExporter:  mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	mockOutputExporter.AssertNumberOfCalls(t, "ExportOutput", 5)
+}
+
+// Failure reason
+func Test_GivenCompileFailure_WhenRunCalled_ThenExpectFailureReasonExported(t *testing.T) {
+	// Given
+	blueprints := []*command.Model{
+		command.New("bash", "-c", "echo Task failed with exit code 65 1>&2 && false"),
+	}
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+	runner.outputExporter = mockOutputExporter
+
+	// When
+	err := runner.Run()
+
+	// Then
+	var runnerErr *RunnerError
+	assert.True(t, errors.As(err, &runnerErr))
+	assert.Equal(t, FailureReasonCompile, runnerErr.Reason)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", FailureReasonOutputKey, "compile_failure")
+}
+
+func Test_GivenBuildFailureWithXcodebuildLog_WhenRunCalled_ThenExpectLogCopiedAndFailureReasonRefined(t *testing.T) {
+	// Given
+	logPath := filepath.Join(givenTempDir(t), "carthage-xcodebuild.AAAA.log")
+	givenFile(t, logPath, "/tmp/Nimble.swift:1:8: error: module compiled with Swift 5.3 cannot be imported by the Swift 5.5 compiler\n")
+	deployDir := givenTempDir(t)
+	blueprints := []*command.Model{
+		command.New("bash", "-c", "echo Task failed with exit code 65. Please check the xcodebuild log for more details: "+logPath+" 1>&2 && false"),
+	}
+	runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+	runner.deployDir = deployDir
+
+	// When
+	err := runner.Run()
+
+	// Then
+	var runnerErr *RunnerError
+	require.True(t, errors.As(err, &runnerErr))
+	assert.Equal(t, logPath, runnerErr.XcodebuildLogPath)
+	assert.Equal(t, FailureReasonSwiftVersionMismatch, runnerErr.Reason)
+	assert.FileExists(t, filepath.Join(deployDir, "carthage-xcodebuild.AAAA.log"))
+}
+
+func Test_GivenFailureReportedOnStdout_WhenRunCalled_ThenExpectFailureClassifiedByStderr(t *testing.T) {
+	testScenarios := []struct {
+		name           string
+		script         string
+		expectedReason FailureReason
+	}{
+		{
+			name:           "compile failure on stderr",
+			script:         "echo 'API rate limit exceeded' && echo '** BUILD FAILED **' 1>&2 && false",
+			expectedReason: FailureReasonCompile,
+		},
+		{
+			name:           "nothing on stderr",
+			script:         "echo 'Could not resolve host: github.com' && false",
+			expectedReason: FailureReasonUnknown,
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		// Given
+		blueprints := []*command.Model{command.New("bash", "-c", testScenario.script)}
+		runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+
+		// When
+		err := runner.Run()
+
+		// Then
+		var runnerErr *RunnerError
+		require.True(t, errors.As(err, &runnerErr), testScenario.name)
+		assert.Equal(t, testScenario.expectedReason, runnerErr.Reason, testScenario.name)
+	}
+}
+
+func Test_GivenOutputLongerThanSize_WhenWrittenToTailBuffer_ThenExpectLastLines(t *testing.T) {
+	// Given
+	buffer := newTailBuffer(10)
+
+	// When
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := buffer.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Then
+	assert.Equal(t, "fourth\n", buffer.String())
+	assert.LessOrEqual(t, len(buffer.buf), 20)
+}
+
+func Test_GivenRequirementsViolatedInFailMode_WhenRunCalled_ThenExpectUnknownFailureReasonExported(t *testing.T) {
+	// Given
+	mockOutputExporter := givenMockOutputExporter().GivenExportOutputSucceeds()
+	runner := Runner{
+		carthageCommand:      "bootstrap",
+		cache:                givenMockCarthageCache(),
+		commandBuilder:       givenStubbedCommandBuilder(),
+		requirementChecker:   givenMockRequirementChecker().GivenCheckSucceeds(givenViolatedCheckResults()),
+		requirementCheckMode: RequirementCheckFail,
+		outputExporter:       mockOutputExporter,
+	}
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.Error(t, err)
+	mockOutputExporter.AssertCalled(t, "ExportOutput", FailureReasonOutputKey, "unknown")
+}
+
 // Retry on failure
 func Test_GivenBootstrapCommandAndSingleNetworkFailure_WhenRunCalled_ThenExpectCommandToBeRetriedAndSucceed(t *testing.T) {
 	// Given
@@ -183,6 +761,62 @@ func Test_GivenUpdateCommandAndPermanentNetworkFailure_WhenRunCalled_ThenExpectC
 	assert.Error(t, err)
 }
 
+func Test_GivenRetryPolicyWithBackoff_WhenRunCalled_ThenExpectExponentialWaits(t *testing.T) {
+	// Given
+	blueprints := []*command.Model{
+		command.New("bash", "-c", "echo Could not resolve host: github.com 1>&2 && false"),
+		command.New("bash", "-c", "echo fatal: early EOF 1>&2 && false"),
+		command.New("echo", "hello"),
+	}
+	var waits []time.Duration
+	runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+	runner.retryPolicy = NewRetryPolicy(2, 3*time.Second, 2)
+	runner.sleepFunc = func(wait time.Duration) { waits = append(waits, wait) }
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second, 6 * time.Second}, waits)
+}
+
+func Test_GivenNotRetryableFailure_WhenRunCalled_ThenExpectCommandNotRetried(t *testing.T) {
+	// Given
+	blueprints := []*command.Model{
+		command.New("bash", "-c", "echo Dependency graph conflict 1>&2 && false"),
+		command.New("echo", "hello"),
+	}
+	var waits []time.Duration
+	runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+	runner.sleepFunc = func(wait time.Duration) { waits = append(waits, wait) }
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.Error(t, err)
+	assert.Empty(t, waits)
+}
+
+func Test_GivenRateLimitFailureWithRetryAfter_WhenRunCalled_ThenExpectWaitUntilReset(t *testing.T) {
+	// Given
+	blueprints := []*command.Model{
+		command.New("bash", "-c", "printf 'API rate limit exceeded\\nRetry-After: 60\\n' 1>&2 && false"),
+		command.New("echo", "hello"),
+	}
+	var waits []time.Duration
+	runner := givenRunnerWithMainAndCommandBuilderCommands("bootstrap", blueprints)
+	runner.sleepFunc = func(wait time.Duration) { waits = append(waits, wait) }
+
+	// When
+	err := runner.Run()
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{60 * time.Second}, waits)
+}
+
 // isCacheAvailable
 func Test_GivenCarthageCacheAvailableFails_WhenIsCacheAvailableCalled_ThenExpectFalse(t *testing.T) {
 	// Given
@@ -230,7 +864,7 @@ func Test_GivenCommadSucceeds_WhenExecuteCommandCalled_ThenExpectNoError(t *test
 	}
 
 	// When
-	err := runner.executeCommand()
+	err := runner.executeCommand(nil, nil)
 
 	// Then
 	assert.NoError(t, err)
@@ -245,6 +879,33 @@ func givenMockCarthageCache() *MockCarthageCache {
 	return new(MockCarthageCache)
 }
 
+func givenMockDependencyArtifactCache() *MockDependencyArtifactCache {
+	return new(MockDependencyArtifactCache)
+}
+
+func givenMockOutputExporter() *MockOutputExporter {
+	return new(MockOutputExporter)
+}
+
+func givenMockRequirementChecker() *MockRequirementChecker {
+	return new(MockRequirementChecker)
+}
+
+func givenViolatedCheckResults() []cartfile.CheckResult {
+	return []cartfile.CheckResult{
+		{
+			Requirement: cartfile.Requirement{Dependency: cartfile.Dependency{Origin: cartfile.OriginGitHub, Source: "Alamofire/Alamofire"}},
+			Status:      cartfile.CheckViolated,
+			Reason:      "5.4.1 does not satisfy ~> 4.0",
+		},
+		{
+			Requirement: cartfile.Requirement{Dependency: cartfile.Dependency{Origin: cartfile.OriginGitHub, Source: "Moya/Moya"}},
+			Status:      cartfile.CheckSatisfied,
+			Reason:      "14.0.0 satisfies ~> 14.0",
+		},
+	}
+}
+
 func givenStubbedCommandBuilder() *MockCommandBuilder {
 	cmd := command.New("echo", "hello")
 	mockCommandBuilder := new(MockCommandBuilder).
@@ -268,6 +929,7 @@ func givenStubbedCommandBuilderReturnFailingCommand() *MockCommandBuilder {
 func givenRunnerWithMainAndCommandBuilderCommands(mainCommand string, commands []*command.Model) Runner {
 	mockCarthageCache := givenMockCarthageCache().
 		GivenIsAvailableSucceeds(false).
+		GivenOutdatedDependenciesSucceeds(nil).
 		GivenCreateIndicatorSucceeds().
 		GivenCommitSucceeds()
 
@@ -275,6 +937,8 @@ func givenRunnerWithMainAndCommandBuilderCommands(mainCommand string, commands [
 		carthageCommand: mainCommand,
 		cache:           mockCarthageCache,
 		commandBuilder:  givenStubbedCommandBuilderReturnsCommands(commands),
+		retryPolicy:     NewRetryPolicy(1, 3*time.Second, 2),
+		sleepFunc:       func(time.Duration) {},
 	}
 }
 
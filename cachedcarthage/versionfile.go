@@ -0,0 +1,154 @@
+package cachedcarthage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	versionFileCommitishKey = "commitish"
+	staticLinking           = "static"
+	staticFrameworksDirName = "Static"
+)
+
+// versionFile is the model of the `.<Dependency>.version` file Carthage writes into Carthage/Build
+// after building (or downloading) a dependency.
+type versionFile struct {
+	Commitish string
+	// Platforms maps the platform names used as Carthage/Build sub-directories (iOS, Mac, tvOS, watchOS)
+	// to the frameworks built for the platform.
+	Platforms map[string][]versionFileFramework
+}
+
+type versionFileFramework struct {
+	Name                  string `json:"name"`
+	Hash                  string `json:"hash"`
+	Linking               string `json:"linking"`
+	SwiftToolchainVersion string `json:"swiftToolchainVersion"`
+	// Container is the xcframework (like Alamofire.xcframework) holding the framework, empty for plain frameworks.
+	Container string `json:"container"`
+	// LibraryIdentifier is the xcframework slice (like ios-arm64_x86_64-simulator) of the framework.
+	LibraryIdentifier string `json:"libraryIdentifier"`
+}
+
+func versionFileName(dependencyName string) string {
+	return fmt.Sprintf(".%s.version", dependencyName)
+}
+
+func parseVersionFile(content []byte) (versionFile, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return versionFile{}, err
+	}
+
+	file := versionFile{Platforms: map[string][]versionFileFramework{}}
+	for key, value := range raw {
+		if key == versionFileCommitishKey {
+			if err := json.Unmarshal(value, &file.Commitish); err != nil {
+				return versionFile{}, fmt.Errorf("invalid %s: %s", key, err)
+			}
+			continue
+		}
+
+		var frameworks []versionFileFramework
+		if err := json.Unmarshal(value, &frameworks); err != nil {
+			return versionFile{}, fmt.Errorf("invalid %s frameworks: %s", key, err)
+		}
+		file.Platforms[key] = frameworks
+	}
+
+	return file, nil
+}
+
+// readVersionFile reads the version file of the given dependency, the returned bool is false if the file does not exist.
+func readVersionFile(buildDir, dependencyName string) (versionFile, bool, error) {
+	content, err := ioutil.ReadFile(filepath.Join(buildDir, versionFileName(dependencyName)))
+	if os.IsNotExist(err) {
+		return versionFile{}, false, nil
+	} else if err != nil {
+		return versionFile{}, false, err
+	}
+
+	file, err := parseVersionFile(content)
+	if err != nil {
+		return versionFile{}, false, fmt.Errorf("failed to parse %s, error: %s", versionFileName(dependencyName), err)
+	}
+	return file, true, nil
+}
+
+// sortedPlatforms returns the platform names of the version file in a stable order.
+func (file versionFile) sortedPlatforms() []string {
+	var platforms []string
+	for platform := range file.Platforms {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// buildProductPaths returns the Carthage/Build relative path of every framework and xcframework slice listed in the version file.
+func (file versionFile) buildProductPaths() []string {
+	var paths []string
+	for _, platform := range file.sortedPlatforms() {
+		for _, framework := range file.Platforms[platform] {
+			paths = append(paths, framework.buildProductPath(platform))
+		}
+	}
+	return paths
+}
+
+// artifactPaths returns the Carthage/Build relative paths holding the build products of the version file:
+// the version file, the frameworks with their dSYMs, and the whole xcframeworks.
+func (file versionFile) artifactPaths(buildDir, dependencyName string) []string {
+	paths := []string{versionFileName(dependencyName)}
+	seen := map[string]bool{}
+	for _, platform := range file.sortedPlatforms() {
+		for _, framework := range file.Platforms[platform] {
+			productPaths := []string{framework.Container}
+			if framework.Container == "" {
+				productPath := framework.buildProductPath(platform)
+				productPaths = []string{productPath}
+				if pathExists(filepath.Join(buildDir, productPath+".dSYM")) {
+					productPaths = append(productPaths, productPath+".dSYM")
+				}
+			}
+
+			for _, pth := range productPaths {
+				if !seen[pth] {
+					seen[pth] = true
+					paths = append(paths, pth)
+				}
+			}
+		}
+	}
+	return paths
+}
+
+func (framework versionFileFramework) buildProductPath(platform string) string {
+	if framework.Container != "" {
+		return filepath.Join(framework.Container, framework.LibraryIdentifier)
+	}
+
+	frameworkName := framework.Name + ".framework"
+	if framework.Linking == staticLinking {
+		return filepath.Join(platform, staticFrameworksDirName, frameworkName)
+	}
+	return filepath.Join(platform, frameworkName)
+}
+
+// missingBuildProducts returns the build products listed in the version file which do not exist in the build dir.
+func (file versionFile) missingBuildProducts(buildDir string) ([]string, error) {
+	var missing []string
+	for _, pth := range file.buildProductPaths() {
+		if _, err := os.Stat(filepath.Join(buildDir, pth)); os.IsNotExist(err) {
+			missing = append(missing, pth)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,103 @@
+package cachedcarthage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const alamofireVersionFileContent = `{
+  "commitish" : "5.4.1",
+  "iOS" : [
+    {
+      "hash" : "a1b2",
+      "name" : "Alamofire",
+      "linking" : "dynamic",
+      "swiftToolchainVersion" : "5.5.2 (swiftlang-1300.0.47.5 clang-1300.0.29.30)"
+    }
+  ],
+  "Mac" : [
+    {
+      "hash" : "c3d4",
+      "name" : "Alamofire",
+      "linking" : "static",
+      "swiftToolchainVersion" : "5.5.2 (swiftlang-1300.0.47.5 clang-1300.0.29.30)"
+    }
+  ],
+  "tvOS" : [
+    {
+      "hash" : "e5f6",
+      "name" : "Alamofire",
+      "container" : "Alamofire.xcframework",
+      "libraryIdentifier" : "tvos-arm64",
+      "linking" : "dynamic",
+      "swiftToolchainVersion" : "5.5.2 (swiftlang-1300.0.47.5 clang-1300.0.29.30)"
+    }
+  ],
+  "watchOS" : [ ]
+}`
+
+// parseVersionFile
+func Test_GivenVersionFileContent_WhenParseVersionFileCalled_ThenExpectFrameworksPerPlatform(t *testing.T) {
+	// When
+	file, err := parseVersionFile([]byte(alamofireVersionFileContent))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "5.4.1", file.Commitish)
+	assert.Equal(t, []string{"Mac", "iOS", "tvOS", "watchOS"}, file.sortedPlatforms())
+	assert.Equal(t, []string{
+		"Mac/Static/Alamofire.framework",
+		"iOS/Alamofire.framework",
+		"Alamofire.xcframework/tvos-arm64",
+	}, file.buildProductPaths())
+}
+
+func Test_GivenInvalidVersionFileContent_WhenParseVersionFileCalled_ThenExpectError(t *testing.T) {
+	// When
+	_, err := parseVersionFile([]byte(`{"commitish": "5.4.1", "iOS": "not a list"}`))
+
+	// Then
+	assert.Error(t, err)
+}
+
+// missingBuildProducts
+func Test_GivenPartialBuildDir_WhenMissingBuildProductsCalled_ThenExpectMissingPaths(t *testing.T) {
+	// Given
+	buildDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(buildDir, "iOS", "Alamofire.framework"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(buildDir, "Alamofire.xcframework", "tvos-arm64"), 0777))
+	file, err := parseVersionFile([]byte(alamofireVersionFileContent))
+	require.NoError(t, err)
+
+	// When
+	missing, err := file.missingBuildProducts(buildDir)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mac/Static/Alamofire.framework"}, missing)
+}
+
+// artifactPaths
+func Test_GivenBuildDir_WhenArtifactPathsCalled_ThenExpectVersionFileFrameworksAndContainers(t *testing.T) {
+	// Given
+	buildDir := t.TempDir()
+	givenFile(t, filepath.Join(buildDir, "iOS", "Alamofire.framework.dSYM", "Contents", "Info.plist"), "")
+	file, err := parseVersionFile([]byte(alamofireVersionFileContent))
+	require.NoError(t, err)
+
+	// When
+	paths := file.artifactPaths(buildDir, "Alamofire")
+
+	// Then
+	assert.Equal(t, []string{
+		".Alamofire.version",
+		"Mac/Static/Alamofire.framework",
+		"iOS/Alamofire.framework",
+		"iOS/Alamofire.framework.dSYM",
+		"Alamofire.xcframework",
+	}, paths)
+}
@@ -0,0 +1,138 @@
+package cachedcarthage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSummarizedCompilerErrors limits the compiler errors printed from the xcodebuild log of a failed build.
+const maxSummarizedCompilerErrors = 10
+
+var (
+	// Carthage prints `xcodebuild output can be found in <path>` when it starts building a dependency,
+	// and `Please check the xcodebuild log for more details: <path>` when the build fails.
+	xcodebuildLogPathPattern = regexp.MustCompile(`(?:xcodebuild output can be found in|xcodebuild log for more details:)\s+(\S+\.log)`)
+	compilerErrorPattern     = regexp.MustCompile(`^(?:(\S.*?):(\d+):(?:(\d+):)?\s+|[\w-]+: )?(?:fatal )?error:\s*(.+)$`)
+	caretLinePattern         = regexp.MustCompile(`^\s*\^[~^]*\s*$`)
+)
+
+// compilerError is an error of an xcodebuild log, with the source line and the caret marker printed after it.
+type compilerError struct {
+	file    string
+	line    int
+	column  int
+	message string
+	context []string
+}
+
+func (e compilerError) String() string {
+	var b strings.Builder
+	if e.file != "" {
+		b.WriteString(filepath.Base(e.file))
+		b.WriteString(":" + strconv.Itoa(e.line))
+		if e.column > 0 {
+			b.WriteString(":" + strconv.Itoa(e.column))
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString("error: " + e.message)
+	for _, line := range e.context {
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
+
+// parseXcodebuildLogPath returns the path of the last xcodebuild log referenced in the Carthage output.
+func parseXcodebuildLogPath(output string) string {
+	matches := xcodebuildLogPathPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
+
+// parseCompilerErrors returns the unique errors of the xcodebuild log, xcodebuild repeats the errors at the end of the build.
+func parseCompilerErrors(r io.Reader) ([]compilerError, error) {
+	var compilerErrors []compilerError
+	seen := map[string]bool{}
+
+	lastErrorIndex := -1
+	var linesSinceError []string
+
+	scanner := bufio.NewScanner(r)
+	// compiler invocations are logged in a single line
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if match := compilerErrorPattern.FindStringSubmatch(line); match != nil {
+			compilerErr := compilerError{file: match[1], message: match[4]}
+			compilerErr.line, _ = strconv.Atoi(match[2])
+			compilerErr.column, _ = strconv.Atoi(match[3])
+
+			key := fmt.Sprintf("%s:%d:%d:%s", compilerErr.file, compilerErr.line, compilerErr.column, compilerErr.message)
+			if seen[key] {
+				lastErrorIndex = -1
+				continue
+			}
+			seen[key] = true
+
+			compilerErrors = append(compilerErrors, compilerErr)
+			lastErrorIndex = len(compilerErrors) - 1
+			linesSinceError = nil
+			continue
+		}
+
+		if lastErrorIndex == -1 {
+			continue
+		}
+
+		linesSinceError = append(linesSinceError, line)
+		if len(linesSinceError) == 2 {
+			if caretLinePattern.MatchString(line) {
+				compilerErrors[lastErrorIndex].context = linesSinceError
+			}
+			lastErrorIndex = -1
+		}
+	}
+
+	return compilerErrors, scanner.Err()
+}
+
+func readCompilerErrors(pth string) ([]compilerError, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return parseCompilerErrors(file)
+}
+
+func copyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	destination, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, source); err != nil {
+		_ = destination.Close()
+		return err
+	}
+	return destination.Close()
+}
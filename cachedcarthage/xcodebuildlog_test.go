@@ -0,0 +1,91 @@
+package cachedcarthage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const failedXcodebuildLog = `CompileSwift normal arm64 /tmp/Checkouts/Alamofire/Source/Session.swift
+/tmp/Checkouts/Alamofire/Source/Session.swift:42:17: error: cannot find 'URLSessionTask' in scope
+        let task: URLSessionTask
+                  ^~~~~~~~~~~~~~
+/tmp/Checkouts/Alamofire/Source/Request.swift:10:1: warning: 'public' modifier is redundant
+/tmp/Checkouts/Alamofire/Source/Request.swift:12:5: error: expected declaration
+clang: error: linker command failed with exit code 1 (use -v to see invocation)
+error: Signing for "Alamofire iOS" requires a development team.
+
+** BUILD FAILED **
+
+/tmp/Checkouts/Alamofire/Source/Session.swift:42:17: error: cannot find 'URLSessionTask' in scope
+        let task: URLSessionTask
+                  ^~~~~~~~~~~~~~
+`
+
+// parseXcodebuildLogPath
+func Test_GivenCarthageOutput_WhenParseXcodebuildLogPathCalled_ThenExpectLastLogPath(t *testing.T) {
+	// Given
+	output := `*** xcodebuild output can be found in /var/folders/x1/T/carthage-xcodebuild.AAAA.log
+*** Building scheme "Alamofire iOS" in Alamofire.xcworkspace
+Build Failed
+	Task failed with exit code 65:
+	/usr/bin/xcrun xcodebuild -workspace /tmp/Checkouts/Alamofire/Alamofire.xcworkspace -scheme Alamofire\ iOS
+
+This usually indicates that project itself failed to compile. Please check the xcodebuild log for more details: /var/folders/x1/T/carthage-xcodebuild.BBBB.log`
+
+	// When
+	actual := parseXcodebuildLogPath(output)
+
+	// Then
+	assert.Equal(t, "/var/folders/x1/T/carthage-xcodebuild.BBBB.log", actual)
+}
+
+func Test_GivenOutputWithoutLogPath_WhenParseXcodebuildLogPathCalled_ThenExpectEmptyPath(t *testing.T) {
+	// When
+	actual := parseXcodebuildLogPath("*** Fetching Alamofire")
+
+	// Then
+	assert.Empty(t, actual)
+}
+
+// parseCompilerErrors
+func Test_GivenXcodebuildLog_WhenParseCompilerErrorsCalled_ThenExpectUniqueErrorsWithContext(t *testing.T) {
+	// When
+	actual, err := parseCompilerErrors(strings.NewReader(failedXcodebuildLog))
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []compilerError{
+		{
+			file:    "/tmp/Checkouts/Alamofire/Source/Session.swift",
+			line:    42,
+			column:  17,
+			message: "cannot find 'URLSessionTask' in scope",
+			context: []string{"        let task: URLSessionTask", "                  ^~~~~~~~~~~~~~"},
+		},
+		{file: "/tmp/Checkouts/Alamofire/Source/Request.swift", line: 12, column: 5, message: "expected declaration"},
+		{message: "linker command failed with exit code 1 (use -v to see invocation)"},
+		{message: `Signing for "Alamofire iOS" requires a development team.`},
+	}, actual)
+}
+
+func Test_GivenCompilerError_WhenStringCalled_ThenExpectCondensedError(t *testing.T) {
+	// Given
+	compilerErr := compilerError{
+		file:    "/tmp/Checkouts/Alamofire/Source/Session.swift",
+		line:    42,
+		column:  17,
+		message: "cannot find 'URLSessionTask' in scope",
+		context: []string{"        let task: URLSessionTask", "                  ^~~~~~~~~~~~~~"},
+	}
+
+	// When
+	actual := compilerErr.String()
+
+	// Then
+	assert.Equal(t, `Session.swift:42:17: error: cannot find 'URLSessionTask' in scope
+        let task: URLSessionTask
+                  ^~~~~~~~~~~~~~`, actual)
+}
@@ -0,0 +1,122 @@
+package cartfile
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+)
+
+// CheckStatus is the outcome of checking a resolved pin against a requirement.
+type CheckStatus string
+
+// Check statuses.
+const (
+	CheckSatisfied  CheckStatus = "satisfied"
+	CheckViolated   CheckStatus = "violated"
+	CheckUnverified CheckStatus = "unverified"
+)
+
+// CheckResult describes if the Cartfile.resolved pin of a dependency satisfies its requirement.
+type CheckResult struct {
+	Requirement Requirement
+	// Pin is the resolved version or commit-ish, empty if the dependency is missing from the Cartfile.resolved.
+	Pin    string
+	Status CheckStatus
+	Reason string
+}
+
+// String ...
+func (result CheckResult) String() string {
+	return fmt.Sprintf("%s: %s", result.Requirement.Name(), result.Reason)
+}
+
+// CheckResolved checks every requirement against the matching Cartfile.resolved entry.
+func CheckResolved(requirements []Requirement, resolved ResolvedFile) []CheckResult {
+	var results []CheckResult
+	for _, requirement := range requirements {
+		results = append(results, checkRequirement(requirement, resolved))
+	}
+	return results
+}
+
+func checkRequirement(requirement Requirement, resolved ResolvedFile) CheckResult {
+	result := CheckResult{Requirement: requirement}
+
+	dependency, ok := resolved.Dependency(requirement.Name())
+	if !ok {
+		result.Status = CheckViolated
+		result.Reason = "missing from Cartfile.resolved"
+		return result
+	}
+	result.Pin = dependency.Pin
+
+	if dependency.Dependency != requirement.Dependency {
+		result.Status = CheckViolated
+		result.Reason = fmt.Sprintf("resolved from %s, but required from %s", dependency.Dependency, requirement.Dependency)
+		return result
+	}
+
+	constraint := requirement.Constraint
+	switch constraint.Operator {
+	case OperatorAny:
+		result.Status = CheckSatisfied
+		result.Reason = fmt.Sprintf("%s satisfies any version", dependency.Pin)
+		return result
+	case OperatorGitReference:
+		switch {
+		case dependency.Pin == constraint.Value:
+			result.Status = CheckSatisfied
+			result.Reason = fmt.Sprintf("%s matches the required git reference", dependency.Pin)
+		case dependency.PinKind() == PinCommitish:
+			result.Status = CheckUnverified
+			result.Reason = fmt.Sprintf("%s can not be matched with git reference %q without fetching the repository", dependency.Pin, constraint.Value)
+		default:
+			result.Status = CheckViolated
+			result.Reason = fmt.Sprintf("%s is not the required git reference %q", dependency.Pin, constraint.Value)
+		}
+		return result
+	}
+
+	pinned, err := dependency.Version()
+	if err != nil {
+		result.Status = CheckViolated
+		result.Reason = fmt.Sprintf("pinned to commit-ish %s, but %s is required", dependency.Pin, constraint)
+		return result
+	}
+
+	if constraint.isSatisfiedBy(pinned) {
+		result.Status = CheckSatisfied
+		result.Reason = fmt.Sprintf("%s satisfies %s", dependency.Pin, constraint)
+	} else {
+		result.Status = CheckViolated
+		result.Reason = fmt.Sprintf("%s does not satisfy %s", dependency.Pin, constraint)
+	}
+	return result
+}
+
+// isSatisfiedBy implements Carthage's version specifier semantics:
+// `~> 2.1` accepts every version from 2.1 below 3.0 (below 0.2 for a 0.1 requirement).
+func (constraint Constraint) isSatisfiedBy(pinned *version.Version) bool {
+	required, err := version.NewSemver(constraint.Value)
+	if err != nil {
+		return false
+	}
+
+	switch constraint.Operator {
+	case OperatorExactly:
+		return pinned.Equal(required)
+	case OperatorAtLeast:
+		return pinned.GreaterThanOrEqual(required)
+	case OperatorCompatible:
+		pinnedSegments, requiredSegments := pinned.Segments(), required.Segments()
+		if pinnedSegments[0] != requiredSegments[0] {
+			return false
+		}
+		if requiredSegments[0] == 0 && pinnedSegments[1] != requiredSegments[1] {
+			return false
+		}
+		return pinned.GreaterThanOrEqual(required)
+	default:
+		return true
+	}
+}
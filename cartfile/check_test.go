@@ -0,0 +1,48 @@
+package cartfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WhenCheckResolvedCalled_ThenExpectStatusPerRequirement(t *testing.T) {
+	testScenarios := []struct {
+		requirement string
+		resolved    string
+		expected    CheckStatus
+	}{
+		{`github "A/A"`, `github "A/A" "1.0.0"`, CheckSatisfied},
+		{`github "A/A" ~> 2.0`, `github "A/A" "2.9.1"`, CheckSatisfied},
+		{`github "A/A" ~> 2.0`, `github "A/A" "3.0.0"`, CheckViolated},
+		{`github "A/A" ~> 2.1.3`, `github "A/A" "2.1.2"`, CheckViolated},
+		{`github "A/A" ~> 0.4`, `github "A/A" "0.5.0"`, CheckViolated},
+		{`github "A/A" ~> 0.4`, `github "A/A" "0.4.7"`, CheckSatisfied},
+		{`github "A/A" >= 1.3`, `github "A/A" "v4.0"`, CheckSatisfied},
+		{`github "A/A" >= 1.3`, `github "A/A" "1.2.9"`, CheckViolated},
+		{`github "A/A" == 4.1`, `github "A/A" "4.1.0"`, CheckSatisfied},
+		{`github "A/A" == 4.1`, `github "A/A" "4.1.1"`, CheckViolated},
+		{`github "A/A" == 4.1`, `github "A/A" "c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2"`, CheckViolated},
+		{`github "A/A" "develop"`, `github "A/A" "c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2"`, CheckUnverified},
+		{`github "A/A" "v1.0"`, `github "A/A" "v1.0"`, CheckSatisfied},
+		{`github "A/A" "v1.0"`, `github "A/A" "1.1.0"`, CheckViolated},
+		{`github "A/A"`, `github "B/B" "1.0.0"`, CheckViolated},
+		{`github "A/A"`, `git "https://github.com/A/A.git" "1.0.0"`, CheckViolated},
+	}
+
+	for _, scenario := range testScenarios {
+		// Given
+		requirements, err := ParseRequirements(scenario.requirement)
+		require.NoError(t, err)
+		resolved, err := ParseResolved(scenario.resolved)
+		require.NoError(t, err)
+
+		// When
+		results := CheckResolved(requirements.Requirements, resolved)
+
+		// Then
+		require.Len(t, results, 1)
+		assert.Equal(t, scenario.expected, results[0].Status, scenario.requirement+" / "+scenario.resolved)
+	}
+}
@@ -0,0 +1,48 @@
+package cartfile
+
+import (
+	"path"
+	"strings"
+)
+
+// Origin is the kind of source a dependency is fetched from.
+type Origin string
+
+// Origins supported by Carthage.
+const (
+	OriginGitHub Origin = "github"
+	OriginGit    Origin = "git"
+	OriginBinary Origin = "binary"
+)
+
+// Dependency identifies a Carthage dependency by its origin and source.
+type Dependency struct {
+	Origin Origin
+	// Source is the `owner/repo` slug or URL of a GitHub dependency, the URL or path of a git repository
+	// or the URL of a binary project specification.
+	Source string
+}
+
+// Name returns the name Carthage uses for the dependency, for example as a command argument
+// or as the directory name in Carthage/Checkouts.
+func (dependency Dependency) Name() string {
+	name := path.Base(strings.TrimSuffix(dependency.Source, "/"))
+	name = strings.TrimSuffix(name, ".git")
+	if dependency.Origin == OriginBinary {
+		name = strings.TrimSuffix(name, ".json")
+	}
+	return name
+}
+
+// URL returns the URL the dependency is fetched from, GitHub slugs are expanded to github.com repository URLs.
+func (dependency Dependency) URL() string {
+	if dependency.Origin == OriginGitHub && !strings.Contains(dependency.Source, "://") {
+		return "https://github.com/" + dependency.Source + ".git"
+	}
+	return dependency.Source
+}
+
+// String ...
+func (dependency Dependency) String() string {
+	return string(dependency.Origin) + " " + quote(dependency.Source)
+}
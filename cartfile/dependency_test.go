@@ -0,0 +1,48 @@
+package cartfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WhenNameCalled_ThenExpectCarthageDependencyName(t *testing.T) {
+	testScenarios := []struct {
+		dependency Dependency
+		expected   string
+	}{
+		{Dependency{Origin: OriginGitHub, Source: "Alamofire/Alamofire"}, "Alamofire"},
+		{Dependency{Origin: OriginGitHub, Source: "https://enterprise.local/owner/Repo/"}, "Repo"},
+		{Dependency{Origin: OriginGit, Source: "https://github.com/ReactiveX/RxSwift.git"}, "RxSwift"},
+		{Dependency{Origin: OriginGit, Source: "../Local.json"}, "Local.json"},
+		{Dependency{Origin: OriginBinary, Source: "https://dl.google.com/FirebaseAnalyticsBinary.json"}, "FirebaseAnalyticsBinary"},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		actual := scenario.dependency.Name()
+
+		// Then
+		assert.Equal(t, scenario.expected, actual)
+	}
+}
+
+func Test_WhenURLCalled_ThenExpectFetchURL(t *testing.T) {
+	testScenarios := []struct {
+		dependency Dependency
+		expected   string
+	}{
+		{Dependency{Origin: OriginGitHub, Source: "Alamofire/Alamofire"}, "https://github.com/Alamofire/Alamofire.git"},
+		{Dependency{Origin: OriginGitHub, Source: "https://enterprise.local/owner/Repo"}, "https://enterprise.local/owner/Repo"},
+		{Dependency{Origin: OriginGit, Source: "git@github.com:ReactiveX/RxSwift.git"}, "git@github.com:ReactiveX/RxSwift.git"},
+		{Dependency{Origin: OriginBinary, Source: "https://dl.google.com/FirebaseAnalyticsBinary.json"}, "https://dl.google.com/FirebaseAnalyticsBinary.json"},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		actual := scenario.dependency.URL()
+
+		// Then
+		assert.Equal(t, scenario.expected, actual)
+	}
+}
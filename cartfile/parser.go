@@ -0,0 +1,93 @@
+package cartfile
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseError describes a syntax error of a Cartfile-like file.
+type ParseError struct {
+	Line   int
+	Text   string
+	Reason string
+}
+
+// Error ...
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s (%s)", e.Line, e.Reason, strings.TrimSpace(e.Text))
+}
+
+// token is a single word of a Cartfile line, either a bare word (like `github` or `~>`) or a quoted string.
+type token struct {
+	value  string
+	quoted bool
+}
+
+// tokenizeLine splits a Cartfile line into tokens, dropping the trailing comment.
+// Quoted strings are taken as-is until the next double quote, like Carthage does.
+func tokenizeLine(line string) ([]token, string) {
+	var tokens []token
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			return tokens, ""
+		case r == '"':
+			end := indexRune(runes, i+1, '"')
+			if end == -1 {
+				return tokens, "unterminated quoted string"
+			}
+			tokens = append(tokens, token{value: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' && runes[i] != '#' {
+				i++
+			}
+			tokens = append(tokens, token{value: string(runes[start:i])})
+		}
+	}
+
+	return tokens, ""
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseOrigin parses the leading origin token of a dependency line.
+func parseOrigin(tokens []token) (Origin, string) {
+	if tokens[0].quoted {
+		return "", "expected dependency origin (github, git or binary)"
+	}
+
+	origin := Origin(tokens[0].value)
+	switch origin {
+	case OriginGitHub, OriginGit, OriginBinary:
+	default:
+		return "", fmt.Sprintf("unknown dependency origin: %s", tokens[0].value)
+	}
+
+	if len(tokens) < 2 || !tokens[1].quoted {
+		return "", fmt.Sprintf("expected quoted source after %s", origin)
+	}
+	if tokens[1].value == "" {
+		return "", "dependency source is empty"
+	}
+
+	return origin, ""
+}
+
+func quote(value string) string {
+	return `"` + value + `"`
+}
@@ -0,0 +1,125 @@
+package cartfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Operator is the kind of a requirement's version constraint.
+type Operator string
+
+// Operators supported by Carthage.
+const (
+	OperatorAny          Operator = ""
+	OperatorCompatible   Operator = "~>"
+	OperatorAtLeast      Operator = ">="
+	OperatorExactly      Operator = "=="
+	OperatorGitReference Operator = "git-reference"
+)
+
+// Constraint is the version requirement of a dependency.
+type Constraint struct {
+	Operator Operator
+	// Value is the version of the ~>, >= and == operators or the branch, tag or commit of a git reference.
+	Value string
+}
+
+// String returns the constraint in the Cartfile format.
+func (constraint Constraint) String() string {
+	switch constraint.Operator {
+	case OperatorAny:
+		return ""
+	case OperatorGitReference:
+		return quote(constraint.Value)
+	default:
+		return string(constraint.Operator) + " " + constraint.Value
+	}
+}
+
+// Requirement is a single entry of a Cartfile or Cartfile.private.
+type Requirement struct {
+	Dependency
+	Constraint Constraint
+}
+
+// String returns the Cartfile line of the requirement.
+func (requirement Requirement) String() string {
+	if requirement.Constraint.Operator == OperatorAny {
+		return requirement.Dependency.String()
+	}
+	return requirement.Dependency.String() + " " + requirement.Constraint.String()
+}
+
+// RequirementFile is the model of a Cartfile or Cartfile.private.
+type RequirementFile struct {
+	Requirements []Requirement
+}
+
+// ParseRequirements parses the content of a Cartfile or Cartfile.private. Empty lines and comments are skipped.
+func ParseRequirements(content string) (RequirementFile, error) {
+	var file RequirementFile
+
+	for i, line := range strings.Split(content, "\n") {
+		tokens, reason := tokenizeLine(line)
+		if reason == "" && len(tokens) == 0 {
+			continue
+		}
+
+		var requirement Requirement
+		if reason == "" {
+			requirement, reason = parseRequirement(tokens)
+		}
+		if reason != "" {
+			return RequirementFile{}, &ParseError{Line: i + 1, Text: line, Reason: reason}
+		}
+
+		file.Requirements = append(file.Requirements, requirement)
+	}
+
+	return file, nil
+}
+
+func parseRequirement(tokens []token) (Requirement, string) {
+	origin, reason := parseOrigin(tokens)
+	if reason != "" {
+		return Requirement{}, reason
+	}
+
+	requirement := Requirement{Dependency: Dependency{Origin: origin, Source: tokens[1].value}}
+	rest := tokens[2:]
+
+	switch {
+	case len(rest) == 0:
+		return requirement, ""
+	case rest[0].quoted:
+		if rest[0].value == "" {
+			return Requirement{}, "git reference is empty"
+		}
+		requirement.Constraint = Constraint{Operator: OperatorGitReference, Value: rest[0].value}
+		rest = rest[1:]
+	default:
+		operator := Operator(rest[0].value)
+		switch operator {
+		case OperatorCompatible, OperatorAtLeast, OperatorExactly:
+		default:
+			return Requirement{}, fmt.Sprintf("unknown version operator: %s", rest[0].value)
+		}
+
+		if len(rest) < 2 || rest[1].quoted {
+			return Requirement{}, fmt.Sprintf("expected version after %s", operator)
+		}
+		if _, err := version.NewSemver(rest[1].value); err != nil {
+			return Requirement{}, fmt.Sprintf("invalid version: %s", rest[1].value)
+		}
+		requirement.Constraint = Constraint{Operator: operator, Value: rest[1].value}
+		rest = rest[2:]
+	}
+
+	if len(rest) > 0 {
+		return Requirement{}, fmt.Sprintf("unexpected token: %s", rest[0].value)
+	}
+
+	return requirement, ""
+}
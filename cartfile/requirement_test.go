@@ -0,0 +1,81 @@
+package cartfile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ParseRequirements
+func Test_GivenValidContent_WhenParseRequirementsCalled_ThenExpectTypedRequirements(t *testing.T) {
+	// Given
+	content := `# Networking
+github "Alamofire/Alamofire" ~> 5.4
+github "Moya/Moya" >= 14.0.0 # at least 14
+github "ReactiveX/RxSwift" == 6.2.0
+git "https://github.com/Quick/Nimble.git" "develop"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json"
+`
+	expected := RequirementFile{Requirements: []Requirement{
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "Alamofire/Alamofire"}, Constraint: Constraint{Operator: OperatorCompatible, Value: "5.4"}},
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "Moya/Moya"}, Constraint: Constraint{Operator: OperatorAtLeast, Value: "14.0.0"}},
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "ReactiveX/RxSwift"}, Constraint: Constraint{Operator: OperatorExactly, Value: "6.2.0"}},
+		{Dependency: Dependency{Origin: OriginGit, Source: "https://github.com/Quick/Nimble.git"}, Constraint: Constraint{Operator: OperatorGitReference, Value: "develop"}},
+		{Dependency: Dependency{Origin: OriginBinary, Source: "https://dl.google.com/FirebaseAnalyticsBinary.json"}},
+	}}
+
+	// When
+	actual, err := ParseRequirements(content)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_GivenInvalidContent_WhenParseRequirementsCalled_ThenExpectParseErrorWithLine(t *testing.T) {
+	testScenarios := []struct {
+		content        string
+		expectedLine   int
+		expectedReason string
+	}{
+		{"github \"Alamofire/Alamofire\" ~< 5.4", 1, "unknown version operator: ~<"},
+		{"github \"Alamofire/Alamofire\"\ngithub \"Moya/Moya\" >=", 2, "expected version after >="},
+		{"github \"Alamofire/Alamofire\" == five", 1, "invalid version: five"},
+		{"github \"Alamofire/Alamofire\" \"develop\" ~> 5.4", 1, "unexpected token: ~>"},
+		{"github \"Alamofire/Alamofire\" \"\"", 1, "git reference is empty"},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		_, err := ParseRequirements(scenario.content)
+
+		// Then
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), scenario.content)
+		assert.Equal(t, scenario.expectedLine, parseErr.Line)
+		assert.Equal(t, scenario.expectedReason, parseErr.Reason)
+	}
+}
+
+// String
+func Test_WhenRequirementStringCalled_ThenExpectCartfileLine(t *testing.T) {
+	testScenarios := []string{
+		`github "Alamofire/Alamofire" ~> 5.4`,
+		`git "https://github.com/Quick/Nimble.git" "develop"`,
+		`binary "https://dl.google.com/FirebaseAnalyticsBinary.json"`,
+	}
+
+	for _, line := range testScenarios {
+		// Given
+		file, err := ParseRequirements(line)
+		require.NoError(t, err)
+
+		// When
+		actual := file.Requirements[0].String()
+
+		// Then
+		assert.Equal(t, line, actual)
+	}
+}
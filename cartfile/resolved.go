@@ -0,0 +1,131 @@
+package cartfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// PinKind tells how a resolved dependency is pinned.
+type PinKind string
+
+// Pin kinds.
+const (
+	PinVersion   PinKind = "version"
+	PinCommitish PinKind = "commitish"
+)
+
+// ResolvedDependency is a single entry of a Cartfile.resolved.
+type ResolvedDependency struct {
+	Dependency
+	// Pin is the version (like `5.4.1` or `v5.4.1`) or the commit-ish (SHA, branch or tag name) the dependency is resolved to.
+	Pin string
+}
+
+// PinKind ...
+func (dependency ResolvedDependency) PinKind() PinKind {
+	if commitSHAPattern.MatchString(dependency.Pin) {
+		return PinCommitish
+	}
+	if _, err := version.NewSemver(dependency.Pin); err == nil {
+		return PinVersion
+	}
+	return PinCommitish
+}
+
+// HasImmutablePin tells if the pin always refers to the same source: a version or a commit SHA, unlike a branch name.
+func (dependency ResolvedDependency) HasImmutablePin() bool {
+	return dependency.PinKind() == PinVersion || commitSHAPattern.MatchString(dependency.Pin)
+}
+
+// Version returns the semantic version of a version pin.
+func (dependency ResolvedDependency) Version() (*version.Version, error) {
+	if dependency.PinKind() != PinVersion {
+		return nil, fmt.Errorf("%s is pinned to a commit-ish (%s), not a version", dependency.Name(), dependency.Pin)
+	}
+	return version.NewSemver(dependency.Pin)
+}
+
+// String returns the Cartfile.resolved line of the dependency.
+func (dependency ResolvedDependency) String() string {
+	return dependency.Dependency.String() + " " + quote(dependency.Pin)
+}
+
+// ResolvedFile is the model of a Cartfile.resolved.
+type ResolvedFile struct {
+	Dependencies []ResolvedDependency
+}
+
+// ParseResolved parses the content of a Cartfile.resolved. Empty lines and comments are skipped.
+func ParseResolved(content string) (ResolvedFile, error) {
+	var file ResolvedFile
+
+	for i, line := range strings.Split(content, "\n") {
+		tokens, reason := tokenizeLine(line)
+		if reason == "" && len(tokens) == 0 {
+			continue
+		}
+
+		var dependency ResolvedDependency
+		if reason == "" {
+			dependency, reason = parseResolvedDependency(tokens)
+		}
+		if reason != "" {
+			return ResolvedFile{}, &ParseError{Line: i + 1, Text: line, Reason: reason}
+		}
+
+		if _, ok := file.Dependency(dependency.Name()); ok {
+			return ResolvedFile{}, &ParseError{Line: i + 1, Text: line, Reason: fmt.Sprintf("duplicate dependency: %s", dependency.Name())}
+		}
+
+		file.Dependencies = append(file.Dependencies, dependency)
+	}
+
+	return file, nil
+}
+
+func parseResolvedDependency(tokens []token) (ResolvedDependency, string) {
+	origin, reason := parseOrigin(tokens)
+	if reason != "" {
+		return ResolvedDependency{}, reason
+	}
+
+	if len(tokens) < 3 || !tokens[2].quoted {
+		return ResolvedDependency{}, "expected quoted version or commit-ish after the source"
+	}
+	if tokens[2].value == "" {
+		return ResolvedDependency{}, "version or commit-ish is empty"
+	}
+	if len(tokens) > 3 {
+		return ResolvedDependency{}, fmt.Sprintf("unexpected token: %s", tokens[3].value)
+	}
+
+	return ResolvedDependency{
+		Dependency: Dependency{Origin: origin, Source: tokens[1].value},
+		Pin:        tokens[2].value,
+	}, ""
+}
+
+// Dependency returns the resolved dependency with the given name.
+func (file ResolvedFile) Dependency(name string) (ResolvedDependency, bool) {
+	for _, dependency := range file.Dependencies {
+		if dependency.Name() == name {
+			return dependency, true
+		}
+	}
+	return ResolvedDependency{}, false
+}
+
+// String serializes the file in the Cartfile.resolved format.
+func (file ResolvedFile) String() string {
+	var builder strings.Builder
+	for _, dependency := range file.Dependencies {
+		builder.WriteString(dependency.String())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
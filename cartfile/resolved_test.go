@@ -0,0 +1,134 @@
+package cartfile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ParseResolved
+func Test_GivenValidContent_WhenParseResolvedCalled_ThenExpectTypedDependencies(t *testing.T) {
+	// Given
+	content := `# Generated by Carthage
+github "Alamofire/Alamofire" "5.4.1"
+git "https://github.com/ReactiveX/RxSwift.git" "6.2.0" # trailing comment
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json#fragment" "8.0.0"
+github "ashleymills/Reachability.swift" "c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2"
+github   "Quick/Nimble"   "develop"
+
+`
+	expected := ResolvedFile{Dependencies: []ResolvedDependency{
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "Alamofire/Alamofire"}, Pin: "5.4.1"},
+		{Dependency: Dependency{Origin: OriginGit, Source: "https://github.com/ReactiveX/RxSwift.git"}, Pin: "6.2.0"},
+		{Dependency: Dependency{Origin: OriginBinary, Source: "https://dl.google.com/FirebaseAnalyticsBinary.json#fragment"}, Pin: "8.0.0"},
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "ashleymills/Reachability.swift"}, Pin: "c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2"},
+		{Dependency: Dependency{Origin: OriginGitHub, Source: "Quick/Nimble"}, Pin: "develop"},
+	}}
+
+	// When
+	actual, err := ParseResolved(content)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_GivenInvalidContent_WhenParseResolvedCalled_ThenExpectParseErrorWithLine(t *testing.T) {
+	testScenarios := []struct {
+		content        string
+		expectedLine   int
+		expectedReason string
+	}{
+		{"github \"Alamofire/Alamofire\" \"5.4.1\"\nsvn \"url\" \"1.0\"", 2, "unknown dependency origin: svn"},
+		{"github \"Alamofire/Alamofire\" \"5.4.1", 1, "unterminated quoted string"},
+		{"\n\ngithub \"Alamofire/Alamofire\"", 3, "expected quoted version or commit-ish after the source"},
+		{"github Alamofire/Alamofire \"5.4.1\"", 1, "expected quoted source after github"},
+		{"github \"Alamofire/Alamofire\" \"5.4.1\" \"extra\"", 1, "unexpected token: extra"},
+		{"github \"Alamofire/Alamofire\" \"\"", 1, "version or commit-ish is empty"},
+		{"github \"A/Alamofire\" \"5.4.1\"\ngithub \"B/Alamofire\" \"5.4.1\"", 2, "duplicate dependency: Alamofire"},
+	}
+
+	for _, scenario := range testScenarios {
+		// When
+		_, err := ParseResolved(scenario.content)
+
+		// Then
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), scenario.content)
+		assert.Equal(t, scenario.expectedLine, parseErr.Line)
+		assert.Equal(t, scenario.expectedReason, parseErr.Reason)
+	}
+}
+
+// String
+func Test_GivenParsedFile_WhenStringCalled_ThenExpectRoundTrip(t *testing.T) {
+	// Given
+	content := `github "Alamofire/Alamofire" "5.4.1"
+git "https://github.com/ReactiveX/RxSwift.git" "6.2.0"
+binary "https://dl.google.com/FirebaseAnalyticsBinary.json" "8.0.0"
+`
+	file, err := ParseResolved(content)
+	require.NoError(t, err)
+
+	// When
+	serialized := file.String()
+
+	// Then
+	assert.Equal(t, content, serialized)
+	reparsed, err := ParseResolved(serialized)
+	require.NoError(t, err)
+	assert.Equal(t, file, reparsed)
+}
+
+// PinKind
+func Test_WhenPinKindCalled_ThenExpectCorrectValue(t *testing.T) {
+	testScenarios := []struct {
+		pin      string
+		expected PinKind
+	}{
+		{"5.4.1", PinVersion},
+		{"v5.4.1", PinVersion},
+		{"1.0.0-beta.1", PinVersion},
+		{"c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2", PinCommitish},
+		{"1234567890123456789012345678901234567890", PinCommitish},
+		{"develop", PinCommitish},
+	}
+
+	for _, scenario := range testScenarios {
+		// Given
+		dependency := ResolvedDependency{Pin: scenario.pin}
+
+		// When
+		actual := dependency.PinKind()
+
+		// Then
+		assert.Equal(t, scenario.expected, actual, scenario.pin)
+	}
+}
+
+// HasImmutablePin
+func Test_WhenHasImmutablePinCalled_ThenExpectCorrectValue(t *testing.T) {
+	testScenarios := []struct {
+		pin      string
+		expected bool
+	}{
+		{"5.4.1", true},
+		{"v5.4.1", true},
+		{"c01bbdf2d633cf049ae1ed1a68a2020a8bda32e2", true},
+		{"c01bbdf", false},
+		{"develop", false},
+	}
+
+	for _, scenario := range testScenarios {
+		// Given
+		dependency := ResolvedDependency{Pin: scenario.pin}
+
+		// When
+		actual := dependency.HasImmutablePin()
+
+		// Then
+		assert.Equal(t, scenario.expected, actual, scenario.pin)
+	}
+}
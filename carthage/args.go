@@ -0,0 +1,147 @@
+package carthage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+)
+
+const cartfileName = "Cartfile"
+
+// argument is a parsed command line argument: an option with its value, or a positional argument.
+type argument struct {
+	// flag is empty for positional arguments.
+	flag     string
+	value    string
+	hasValue bool
+}
+
+func (arg argument) tokens() []string {
+	if arg.flag == "" {
+		return []string{arg.value}
+	}
+	if arg.hasValue {
+		return []string{arg.flag, arg.value}
+	}
+	return []string{arg.flag}
+}
+
+// key identifies the option, a bool option and its negated form have the same key.
+func (arg argument) key() string {
+	if name := strings.TrimPrefix(arg.flag, "--no-"); name != arg.flag && optionKindOf("--"+name) == boolOption {
+		return "--" + name
+	}
+	return arg.flag
+}
+
+// NormalizeArgs returns the arguments in the form Carthage accepts: `--option=value` is split to `--option value`,
+// and only the last one of a repeated option is kept. The returned warnings describe the dropped options.
+func NormalizeArgs(args []string) ([]string, []string) {
+	parsed := parseArguments(args)
+
+	last := map[string]int{}
+	for i, arg := range parsed {
+		if arg.flag != "" {
+			last[arg.key()] = i
+		}
+	}
+
+	var normalized, warnings []string
+	for i, arg := range parsed {
+		if arg.flag != "" && last[arg.key()] != i {
+			kept := parsed[last[arg.key()]]
+			warnings = append(warnings, fmt.Sprintf("%s is given multiple times, using the last one: %s", arg.key(), strings.Join(kept.tokens(), " ")))
+			continue
+		}
+		normalized = append(normalized, arg.tokens()...)
+	}
+	return normalized, warnings
+}
+
+func parseArguments(args []string) []argument {
+	var parsed []argument
+	for i := 0; i < len(args); i++ {
+		token := args[i]
+		if !strings.HasPrefix(token, "--") {
+			parsed = append(parsed, argument{value: token})
+			continue
+		}
+
+		if parts := strings.SplitN(token, "=", 2); len(parts) == 2 && optionKindOf(parts[0]) == valueOption {
+			parsed = append(parsed, argument{flag: parts[0], value: parts[1], hasValue: true})
+			continue
+		}
+
+		if optionKindOf(token) == valueOption && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			parsed = append(parsed, argument{flag: token, value: args[i+1], hasValue: true})
+			i++
+			continue
+		}
+
+		parsed = append(parsed, argument{flag: token})
+	}
+	return parsed
+}
+
+// PositionalArgs returns the arguments which are not options or option values, like the dependencies of `carthage update`.
+func PositionalArgs(args []string) []string {
+	var positional []string
+	for _, arg := range parseArguments(args) {
+		if arg.flag == "" {
+			positional = append(positional, arg.value)
+		}
+	}
+	return positional
+}
+
+// optionKindOf returns the kind of the flag in any of the commands, -1 if no command has such option.
+func optionKindOf(flag string) optionKind {
+	for _, command := range commands {
+		if kind, ok := command.options[strings.TrimPrefix(flag, "--")]; ok {
+			return kind
+		}
+	}
+	return -1
+}
+
+// ResolveProjectDirectory resolves the project directory of the options against the base dir,
+// and replaces it with the absolute path in the options and the arguments as well.
+// The project directory needs to contain a Cartfile.
+func ResolveProjectDirectory(options Options, args []string, baseDir string) (Options, []string, error) {
+	if options.ProjectDirectory == "" {
+		return options, args, nil
+	}
+
+	dir := options.ProjectDirectory
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return Options{}, nil, err
+	}
+
+	if exists, err := pathutil.IsDirExists(dir); err != nil {
+		return Options{}, nil, err
+	} else if !exists {
+		return Options{}, nil, fmt.Errorf("project directory does not exist: %s", dir)
+	}
+	if exists, err := pathutil.IsPathExists(filepath.Join(dir, cartfileName)); err != nil {
+		return Options{}, nil, err
+	} else if !exists {
+		return Options{}, nil, fmt.Errorf("no %s found in the project directory: %s", cartfileName, dir)
+	}
+
+	resolvedArgs := make([]string, len(args))
+	copy(resolvedArgs, args)
+	for i := 0; i+1 < len(resolvedArgs); i++ {
+		if resolvedArgs[i] == projectDirectoryFlag {
+			resolvedArgs[i+1] = dir
+		}
+	}
+
+	options.ProjectDirectory = dir
+	return options, resolvedArgs, nil
+}
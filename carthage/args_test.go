@@ -0,0 +1,116 @@
+package carthage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WhenNormalizeArgsCalled_ThenExpectCarthageArgs(t *testing.T) {
+	testScenarios := []struct {
+		name             string
+		args             []string
+		expectedArgs     []string
+		expectedWarnings []string
+	}{
+		{
+			name:         "separate values",
+			args:         []string{"--platform", "ios", "--cache-builds", "Alamofire"},
+			expectedArgs: []string{"--platform", "ios", "--cache-builds", "Alamofire"},
+		},
+		{
+			name:         "equal sign syntax",
+			args:         []string{"--project-directory=App/", "--platform=ios,tvos", "--cache-builds"},
+			expectedArgs: []string{"--project-directory", "App/", "--platform", "ios,tvos", "--cache-builds"},
+		},
+		{
+			name:             "repeated value option",
+			args:             []string{"--platform", "ios", "--cache-builds", "--platform=tvos"},
+			expectedArgs:     []string{"--cache-builds", "--platform", "tvos"},
+			expectedWarnings: []string{"--platform is given multiple times, using the last one: --platform tvos"},
+		},
+		{
+			name:             "negated bool option",
+			args:             []string{"--use-binaries", "--no-use-binaries"},
+			expectedArgs:     []string{"--no-use-binaries"},
+			expectedWarnings: []string{"--use-binaries is given multiple times, using the last one: --no-use-binaries"},
+		},
+		{
+			name:         "missing value",
+			args:         []string{"--platform", "--cache-builds"},
+			expectedArgs: []string{"--platform", "--cache-builds"},
+		},
+		{
+			name:         "unknown option",
+			args:         []string{"--platfrom=ios"},
+			expectedArgs: []string{"--platfrom=ios"},
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		// When
+		actualArgs, actualWarnings := NormalizeArgs(testScenario.args)
+
+		// Then
+		assert.Equal(t, testScenario.expectedArgs, actualArgs, testScenario.name)
+		assert.Equal(t, testScenario.expectedWarnings, actualWarnings, testScenario.name)
+	}
+}
+
+func Test_GivenRelativeProjectDirectory_WhenResolveProjectDirectoryCalled_ThenExpectAbsolutePath(t *testing.T) {
+	// Given
+	sourceDir := t.TempDir()
+	projectDir := filepath.Join(sourceDir, "App")
+	require.NoError(t, os.MkdirAll(projectDir, 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(projectDir, "Cartfile"), nil, 0666))
+	options := Options{ProjectDirectory: "App/"}
+	args := []string{"--project-directory", "App/", "--platform", "ios"}
+
+	// When
+	actualOptions, actualArgs, err := ResolveProjectDirectory(options, args, sourceDir)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, projectDir, actualOptions.ProjectDirectory)
+	assert.Equal(t, []string{"--project-directory", projectDir, "--platform", "ios"}, actualArgs)
+	assert.Equal(t, "App/", args[1])
+}
+
+func Test_GivenInvalidProjectDirectory_WhenResolveProjectDirectoryCalled_ThenExpectError(t *testing.T) {
+	// Given
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "Empty"), 0777))
+
+	// When
+	_, _, missingDirErr := ResolveProjectDirectory(Options{ProjectDirectory: "Missing"}, nil, sourceDir)
+	_, _, missingCartfileErr := ResolveProjectDirectory(Options{ProjectDirectory: "Empty"}, nil, sourceDir)
+
+	// Then
+	assert.EqualError(t, missingDirErr, "project directory does not exist: "+filepath.Join(sourceDir, "Missing"))
+	assert.EqualError(t, missingCartfileErr, "no Cartfile found in the project directory: "+filepath.Join(sourceDir, "Empty"))
+}
+
+func Test_GivenNoProjectDirectory_WhenResolveProjectDirectoryCalled_ThenExpectUnchangedArgs(t *testing.T) {
+	// Given
+	args := []string{"--platform", "ios"}
+
+	// When
+	actualOptions, actualArgs, err := ResolveProjectDirectory(Options{}, args, "/source")
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, Options{}, actualOptions)
+	assert.Equal(t, args, actualArgs)
+}
+
+func Test_WhenPositionalArgsCalled_ThenExpectDependencies(t *testing.T) {
+	// When
+	positional := PositionalArgs([]string{"--platform", "iOS", "Alamofire", "--use-xcframeworks", "--derived-data=dd", "Moya"})
+
+	// Then
+	assert.Equal(t, []string{"Alamofire", "Moya"}, positional)
+}
@@ -0,0 +1,265 @@
+package carthage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Carthage commands.
+const (
+	BootstrapCommand      = "bootstrap"
+	UpdateCommand         = "update"
+	BuildCommand          = "build"
+	CheckoutCommand       = "checkout"
+	OutdatedCommand       = "outdated"
+	ValidateCommand       = "validate"
+	FetchCommand          = "fetch"
+	ArchiveCommand        = "archive"
+	CopyFrameworksCommand = "copy-frameworks"
+	VersionCommand        = "version"
+)
+
+// optionKind tells how an option is given on the command line.
+type optionKind int
+
+const (
+	// switchOption is a flag without a value, like `--cache-builds`.
+	switchOption optionKind = iota
+	// valueOption is followed by its value, like `--platform ios`.
+	valueOption
+	// boolOption is a flag which can be negated: `--use-binaries` and `--no-use-binaries` are both valid.
+	boolOption
+)
+
+type option struct {
+	name string
+	kind optionKind
+}
+
+var (
+	colorOption            = option{"color", valueOption}
+	projectDirectoryOption = option{"project-directory", valueOption}
+	verboseOption          = option{"verbose", switchOption}
+	logPathOption          = option{"log-path", valueOption}
+	useSSHOption           = option{"use-ssh", switchOption}
+	useNetrcOption         = option{"use-netrc", switchOption}
+	useBinariesOption      = option{"use-binaries", boolOption}
+	newResolverOption      = option{"new-resolver", switchOption}
+
+	// The options are listed by `carthage help <command>` of Carthage 0.38.
+	buildOptions = []option{
+		{"configuration", valueOption},
+		{"platform", valueOption},
+		{"toolchain", valueOption},
+		{"derived-data", valueOption},
+		{"cache-builds", switchOption},
+		{"use-xcframeworks", switchOption},
+		useBinariesOption,
+	}
+	checkoutOptions = []option{
+		useSSHOption,
+		{"use-submodules", switchOption},
+		useNetrcOption,
+	}
+	commonOptions = []option{colorOption, projectDirectoryOption}
+)
+
+// Command is a Carthage command and the options it accepts.
+type Command struct {
+	Name    string
+	options map[string]optionKind
+	// acceptsArguments tells if the command accepts positional arguments, like the dependency names of `bootstrap`.
+	acceptsArguments bool
+}
+
+func newCommand(name string, acceptsArguments bool, optionGroups ...[]option) Command {
+	command := Command{Name: name, options: map[string]optionKind{}, acceptsArguments: acceptsArguments}
+	for _, group := range optionGroups {
+		for _, opt := range group {
+			command.options[opt.name] = opt.kind
+		}
+	}
+	return command
+}
+
+var commands = []Command{
+	newCommand(BootstrapCommand, true, buildOptions, checkoutOptions, commonOptions,
+		[]option{{"checkout", boolOption}, {"build", boolOption}, newResolverOption, verboseOption, logPathOption}),
+	newCommand(UpdateCommand, true, buildOptions, checkoutOptions, commonOptions,
+		[]option{{"checkout", boolOption}, {"build", boolOption}, newResolverOption, verboseOption, logPathOption}),
+	newCommand(BuildCommand, true, buildOptions, commonOptions,
+		[]option{{"skip-current", boolOption}, {"archive", switchOption}, {"archive-output", valueOption}, verboseOption, logPathOption}),
+	newCommand(CheckoutCommand, true, checkoutOptions, commonOptions,
+		[]option{useBinariesOption}),
+	newCommand(OutdatedCommand, false, commonOptions,
+		[]option{useSSHOption, useNetrcOption, verboseOption, {"xcode-warnings", switchOption}}),
+	newCommand(ValidateCommand, false, commonOptions),
+	newCommand(FetchCommand, true, []option{colorOption}),
+	newCommand(ArchiveCommand, true, commonOptions,
+		[]option{{"output", valueOption}}),
+	newCommand(CopyFrameworksCommand, false),
+	newCommand(VersionCommand, false),
+}
+
+// CommandNames returns the names of the supported Carthage commands.
+func CommandNames() []string {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+	return names
+}
+
+// ParseCommand returns the Carthage command of the name, or an error suggesting the closest command name.
+func ParseCommand(name string) (Command, error) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, nil
+		}
+	}
+
+	message := fmt.Sprintf("unknown Carthage command: %s", name)
+	if suggestion := closest(name, CommandNames()); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %s?", suggestion)
+	} else {
+		message += fmt.Sprintf(", supported commands: %s", strings.Join(CommandNames(), ", "))
+	}
+	return Command{}, errors.New(message)
+}
+
+// ValidateOptions checks if the command accepts every option of the options, and every value option is followed by its value.
+func (command Command) ValidateOptions(options []string) error {
+	for i := 0; i < len(options); i++ {
+		arg := options[i]
+		if !strings.HasPrefix(arg, "--") {
+			if !command.acceptsArguments {
+				return fmt.Errorf("carthage %s does not accept arguments, got: %s", command.Name, arg)
+			}
+			continue
+		}
+
+		kind, ok := command.optionKind(arg)
+		if !ok {
+			message := fmt.Sprintf("unknown option for carthage %s: %s", command.Name, arg)
+			if suggestion := closest(arg, command.flags()); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %s?", suggestion)
+			}
+			return errors.New(message)
+		}
+
+		if kind == valueOption {
+			if i+1 == len(options) || strings.HasPrefix(options[i+1], "--") {
+				return fmt.Errorf("missing value of %s", arg)
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// FilterOptions returns the options accepted by the command, with their values, dropping the rest of the options and the arguments.
+// It is used to run a command with the options given for another one, like `carthage build` with the options of `bootstrap`.
+func (command Command) FilterOptions(options []string) []string {
+	var filtered []string
+	for i := 0; i < len(options); i++ {
+		arg := options[i]
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+
+		kind, accepted := command.optionKind(arg)
+		hasValue := optionKindOf(arg) == valueOption && i+1 < len(options) && !strings.HasPrefix(options[i+1], "--")
+		if accepted {
+			filtered = append(filtered, arg)
+			if kind == valueOption && hasValue {
+				filtered = append(filtered, options[i+1])
+			}
+		}
+		if hasValue {
+			i++
+		}
+	}
+	return filtered
+}
+
+// optionKind returns the kind of the flag, the returned bool is false if the command has no such option.
+func (command Command) optionKind(flag string) (optionKind, bool) {
+	name := strings.TrimPrefix(flag, "--")
+	if kind, ok := command.options[name]; ok {
+		return kind, true
+	}
+	if negated := strings.TrimPrefix(name, "no-"); negated != name {
+		if kind, ok := command.options[negated]; ok && kind == boolOption {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// flags returns every flag the command accepts, including the negated bool options.
+func (command Command) flags() []string {
+	var flags []string
+	for name, kind := range command.options {
+		flags = append(flags, "--"+name)
+		if kind == boolOption {
+			flags = append(flags, "--no-"+name)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// closest returns the candidate closest to the value, if it is close enough to be a typo.
+func closest(value string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := editDistance(value, candidate)
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	if bestDistance == -1 || bestDistance > 2 && bestDistance > len(value)/3 {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Damerau-Levenshtein (optimal string alignment) distance of the strings,
+// so a swap of adjacent characters, the most common typo, counts as a single edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minimum(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minimum(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minimum(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package carthage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WhenParseCommandCalled_ThenExpectCommandOrSuggestion(t *testing.T) {
+	testScenarios := []struct {
+		name        string
+		expectedErr string
+	}{
+		{name: "bootstrap"},
+		{name: "copy-frameworks"},
+		{name: "boostrap", expectedErr: "unknown Carthage command: boostrap, did you mean bootstrap?"},
+		{name: "udpate", expectedErr: "unknown Carthage command: udpate, did you mean update?"},
+		{name: "install", expectedErr: "unknown Carthage command: install, supported commands: bootstrap, update, build, checkout, outdated, validate, fetch, archive, copy-frameworks, version"},
+	}
+
+	for _, testScenario := range testScenarios {
+		// When
+		command, err := ParseCommand(testScenario.name)
+
+		// Then
+		if testScenario.expectedErr != "" {
+			assert.EqualError(t, err, testScenario.expectedErr)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, testScenario.name, command.Name)
+	}
+}
+
+func Test_WhenValidateOptionsCalled_ThenExpectInvalidOptionsReported(t *testing.T) {
+	testScenarios := []struct {
+		command     string
+		options     []string
+		expectedErr string
+	}{
+		{
+			command: "bootstrap",
+			options: []string{"--platform", "ios", "--cache-builds", "--no-use-binaries", "--use-xcframeworks", "Alamofire"},
+		},
+		{
+			command: "build",
+			options: []string{"--no-skip-current", "--configuration", "Debug"},
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--platfrom", "ios"},
+			expectedErr: "unknown option for carthage bootstrap: --platfrom, did you mean --platform?",
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--no-use-binary"},
+			expectedErr: "unknown option for carthage bootstrap: --no-use-binary, did you mean --no-use-binaries?",
+		},
+		{
+			command:     "build",
+			options:     []string{"--use-ssh"},
+			expectedErr: "unknown option for carthage build: --use-ssh",
+		},
+		{
+			command: "build",
+			options: []string{"--archive", "--archive-output", "Frameworks.zip"},
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--archive"},
+			expectedErr: "unknown option for carthage bootstrap: --archive",
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--no-cache-builds"},
+			expectedErr: "unknown option for carthage bootstrap: --no-cache-builds, did you mean --cache-builds?",
+		},
+		{
+			command:     "checkout",
+			options:     []string{"--new-resolver"},
+			expectedErr: "unknown option for carthage checkout: --new-resolver",
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--no-verbose"},
+			expectedErr: "unknown option for carthage bootstrap: --no-verbose, did you mean --verbose?",
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--platform", "--cache-builds"},
+			expectedErr: "missing value of --platform",
+		},
+		{
+			command:     "bootstrap",
+			options:     []string{"--configuration"},
+			expectedErr: "missing value of --configuration",
+		},
+		{
+			command:     "validate",
+			options:     []string{"Alamofire"},
+			expectedErr: "carthage validate does not accept arguments, got: Alamofire",
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		// Given
+		command, err := ParseCommand(testScenario.command)
+		require.NoError(t, err)
+
+		// When
+		err = command.ValidateOptions(testScenario.options)
+
+		// Then
+		if testScenario.expectedErr == "" {
+			assert.NoError(t, err, testScenario.options)
+		} else {
+			assert.EqualError(t, err, testScenario.expectedErr)
+		}
+	}
+}
+
+func Test_WhenEditDistanceCalled_ThenExpectTranspositionsCountedOnce(t *testing.T) {
+	assert.Equal(t, 0, editDistance("platform", "platform"))
+	assert.Equal(t, 1, editDistance("platfrom", "platform"))
+	assert.Equal(t, 1, editDistance("platforms", "platform"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+}
+
+func Test_GivenBootstrapOptions_WhenFilterOptionsCalledForBuild_ThenExpectBuildOptions(t *testing.T) {
+	// Given
+	command, err := ParseCommand(BuildCommand)
+	require.NoError(t, err)
+	options := []string{"--platform", "ios", "--use-ssh", "--new-resolver", "--project-directory", "/app", "--log-path", "/tmp/log", "--no-use-binaries", "Alamofire"}
+
+	// When
+	filtered := command.FilterOptions(options)
+
+	// Then
+	assert.Equal(t, []string{"--platform", "ios", "--project-directory", "/app", "--log-path", "/tmp/log", "--no-use-binaries"}, filtered)
+}
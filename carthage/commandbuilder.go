@@ -9,21 +9,21 @@ import (
 )
 
 // CLIBuilder can be used to build cli Carthage commands.
+// Builders are immutable: every method returns a new builder, so the same builder can be used to build the command of each retry.
 type CLIBuilder struct {
-	cmd *command.Model
+	envs []string
+	args []string
 }
 
 // NewCLIBuilder ...
 func NewCLIBuilder() CLIBuilder {
-	return CLIBuilder{
-		cmd: command.New("carthage"),
-	}
+	return CLIBuilder{}
 }
 
 // AddGitHubToken appends the provided GitHub token to the builder.
 func (builder CLIBuilder) AddGitHubToken(githubToken stepconf.Secret) cachedcarthage.CommandBuilder {
 	if githubToken != "" {
-		builder.cmd.AppendEnvs(fmt.Sprintf("GITHUB_ACCESS_TOKEN=%s", string(githubToken)))
+		builder.envs = appendCopy(builder.envs, fmt.Sprintf("GITHUB_ACCESS_TOKEN=%s", string(githubToken)))
 	}
 	return builder
 }
@@ -31,18 +31,33 @@ func (builder CLIBuilder) AddGitHubToken(githubToken stepconf.Secret) cachedcart
 // AddXCConfigFile appends the provided .xcconfig file path to the builder.
 func (builder CLIBuilder) AddXCConfigFile(path string) cachedcarthage.CommandBuilder {
 	if path != "" {
-		builder.cmd.AppendEnvs(fmt.Sprintf("XCODE_XCCONFIG_FILE=%s", path))
+		builder.envs = appendCopy(builder.envs, fmt.Sprintf("XCODE_XCCONFIG_FILE=%s", path))
 	}
 	return builder
 }
 
+// AddEnvs appends the provided envs (in KEY=value format) to the builder.
+func (builder CLIBuilder) AddEnvs(envs ...string) cachedcarthage.CommandBuilder {
+	builder.envs = appendCopy(builder.envs, envs...)
+	return builder
+}
+
 // Append adds the arguments to the builder.
 func (builder CLIBuilder) Append(args ...string) cachedcarthage.CommandBuilder {
-	builder.cmd.GetCmd().Args = append(builder.cmd.GetCmd().Args, args...)
+	builder.args = appendCopy(builder.args, args...)
 	return builder
 }
 
-// Command returns the built command.
+// Command returns a new command built from the builder.
 func (builder CLIBuilder) Command() *command.Model {
-	return builder.cmd
+	cmd := command.New("carthage", builder.args...)
+	if len(builder.envs) > 0 {
+		cmd.AppendEnvs(builder.envs...)
+	}
+	return cmd
+}
+
+// appendCopy appends the values to a copy of the slice, so builders derived from the same builder do not share their backing arrays.
+func appendCopy(slice []string, values ...string) []string {
+	return append(append([]string{}, slice...), values...)
 }
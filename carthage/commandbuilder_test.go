@@ -49,3 +49,41 @@ func Test_WhenXCConfigFileAppended_ThenResultCommandContainsPath(t *testing.T) {
 	assert.Equal(t, expectedCommand, command.PrintableCommandArgs())
 	assert.Contains(t, command.GetCmd().Env, expectedEnv)
 }
+
+func Test_GivenGitHubTokenAndXCConfigFile_WhenCommandCalled_ThenResultCommandContainsBothEnvs(t *testing.T) {
+	// Given
+	builder := NewCLIBuilder()
+
+	// When
+	command := builder.AddGitHubToken("nice_token").AddXCConfigFile("/path/file.xcconfig").Command()
+
+	// Then
+	assert.Contains(t, command.GetCmd().Env, "GITHUB_ACCESS_TOKEN=nice_token")
+	assert.Contains(t, command.GetCmd().Env, "XCODE_XCCONFIG_FILE=/path/file.xcconfig")
+}
+
+func Test_GivenBuilder_WhenBuiltTwice_ThenExpectIndependentCommands(t *testing.T) {
+	// Given
+	builder := NewCLIBuilder().Append("bootstrap")
+
+	// When
+	first := builder.Append("--platform", "ios").Command()
+	second := builder.Append("Alamofire").Command()
+
+	// Then
+	assert.Equal(t, `carthage "bootstrap" "--platform" "ios"`, first.PrintableCommandArgs())
+	assert.Equal(t, `carthage "bootstrap" "Alamofire"`, second.PrintableCommandArgs())
+	assert.NotSame(t, first, second)
+}
+
+func Test_WhenEnvsAppended_ThenResultCommandContainsEnvs(t *testing.T) {
+	// Given
+	builder := NewCLIBuilder()
+
+	// When
+	command := builder.AddEnvs("GIT_ALLOW_PROTOCOL=file", "GIT_TERMINAL_PROMPT=0").Append("version").Command()
+
+	// Then
+	assert.Contains(t, command.GetCmd().Env, "GIT_ALLOW_PROTOCOL=file")
+	assert.Contains(t, command.GetCmd().Env, "GIT_TERMINAL_PROMPT=0")
+}
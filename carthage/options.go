@@ -0,0 +1,184 @@
+package carthage
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	platformFlag         = "--platform"
+	configurationFlag    = "--configuration"
+	derivedDataFlag      = "--derived-data"
+	toolchainFlag        = "--toolchain"
+	projectDirectoryFlag = "--project-directory"
+	useXCFrameworksFlag  = "--use-xcframeworks"
+	useSSHFlag           = "--use-ssh"
+	useSubmodulesFlag    = "--use-submodules"
+	noUseBinariesFlag    = "--no-use-binaries"
+	cacheBuildsFlag      = "--cache-builds"
+	newResolverFlag      = "--new-resolver"
+)
+
+// Options are the common Carthage options, which the step logic relies on.
+// They can be given by dedicated step inputs and in the custom options as well.
+type Options struct {
+	// Platform is the value of `--platform`, a comma or space separated list of platforms.
+	Platform         string
+	Configuration    string
+	DerivedData      string
+	Toolchain        string
+	ProjectDirectory string
+	UseXCFrameworks  bool
+	UseSSH           bool
+	UseSubmodules    bool
+	NoUseBinaries    bool
+	CacheBuilds      bool
+	NewResolver      bool
+}
+
+type valueField struct {
+	flag  string
+	value *string
+}
+
+type switchField struct {
+	flag    string
+	enabled *bool
+}
+
+func (options *Options) valueFields() []valueField {
+	return []valueField{
+		{platformFlag, &options.Platform},
+		{configurationFlag, &options.Configuration},
+		{derivedDataFlag, &options.DerivedData},
+		{toolchainFlag, &options.Toolchain},
+		{projectDirectoryFlag, &options.ProjectDirectory},
+	}
+}
+
+func (options *Options) switchFields() []switchField {
+	return []switchField{
+		{useXCFrameworksFlag, &options.UseXCFrameworks},
+		{useSSHFlag, &options.UseSSH},
+		{useSubmodulesFlag, &options.UseSubmodules},
+		{noUseBinariesFlag, &options.NoUseBinaries},
+		{cacheBuildsFlag, &options.CacheBuilds},
+		{newResolverFlag, &options.NewResolver},
+	}
+}
+
+// platformAliases are the other names Carthage accepts for a platform.
+var platformAliases = map[string]string{
+	"mac": "macos",
+	"osx": "macos",
+}
+
+// Platforms returns the lowercased platforms of the `--platform` option, with the aliases replaced by the platform name.
+func (options Options) Platforms() []string {
+	var platforms []string
+	seen := map[string]bool{}
+	for _, platform := range strings.FieldsFunc(options.Platform, func(r rune) bool { return r == ',' || r == ' ' }) {
+		platform = strings.ToLower(platform)
+		if name, ok := platformAliases[platform]; ok {
+			platform = name
+		}
+		if !seen[platform] {
+			seen[platform] = true
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms
+}
+
+// MergeOptions merges the options given by the step inputs with the custom options.
+// It returns the merged options and the command line arguments: the arguments of the input options
+// not given in the custom options and accepted by the command, followed by the custom options.
+// An option given by both, with different values, is a conflict.
+func (command Command) MergeOptions(inputOptions Options, customOptions []string) (Options, []string, error) {
+	merged := inputOptions
+	var args []string
+
+	for _, field := range merged.valueFields() {
+		customValue, ok := parseValue(customOptions, field.flag)
+		if !ok {
+			if *field.value != "" {
+				args = append(args, field.flag, *field.value)
+			}
+			continue
+		}
+		if *field.value != "" && !equalValues(field.flag, *field.value, customValue) {
+			return Options{}, nil, fmt.Errorf("conflicting %s values: %s (input) and %s (custom options)", field.flag, *field.value, customValue)
+		}
+		*field.value = customValue
+	}
+
+	for _, field := range merged.switchFields() {
+		enabled, negated := hasFlag(customOptions, field.flag), isNegatable(field.flag) && hasFlag(customOptions, oppositeFlag(field.flag))
+		if *field.enabled && negated {
+			return Options{}, nil, fmt.Errorf("conflicting options: %s (input) and %s (custom options)", field.flag, oppositeFlag(field.flag))
+		}
+		if *field.enabled && !enabled {
+			args = append(args, field.flag)
+		}
+		*field.enabled = *field.enabled || enabled
+	}
+
+	return merged, append(command.FilterOptions(args), customOptions...), nil
+}
+
+// equalValues tells if the values of the option are the same, the platforms of `--platform` are compared as sets.
+func equalValues(flag, value, other string) bool {
+	if flag != platformFlag {
+		return value == other
+	}
+
+	platforms, otherPlatforms := platformSet(value), platformSet(other)
+	if len(platforms) != len(otherPlatforms) {
+		return false
+	}
+	for platform := range platforms {
+		if !otherPlatforms[platform] {
+			return false
+		}
+	}
+	return true
+}
+
+func platformSet(value string) map[string]bool {
+	set := map[string]bool{}
+	for _, platform := range (Options{Platform: value}).Platforms() {
+		set[platform] = true
+	}
+	return set
+}
+
+// isNegatable tells if the flag has an opposite, like `--no-use-binaries` for `--use-binaries`.
+func isNegatable(flag string) bool {
+	return optionKindOf("--"+strings.TrimPrefix(strings.TrimPrefix(flag, "--"), "no-")) == boolOption
+}
+
+// oppositeFlag returns the negated form of the flag, like `--no-use-binaries` for `--use-binaries`, and vice versa.
+func oppositeFlag(flag string) string {
+	if name := strings.TrimPrefix(flag, "--no-"); name != flag {
+		return "--" + name
+	}
+	return "--no-" + strings.TrimPrefix(flag, "--")
+}
+
+func parseValue(options []string, flag string) (string, bool) {
+	for i, option := range options {
+		if option == flag && i+1 < len(options) {
+			return options[i+1], true
+		}
+	}
+	return "", false
+}
+
+func hasFlag(options []string, flag string) bool {
+	for _, option := range options {
+		if option == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package carthage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GivenPlatformOption_WhenPlatformsCalled_ThenExpectLowercasedPlatforms(t *testing.T) {
+	// Given
+	options := Options{Platform: "iOS,tvOS macOS"}
+
+	// When
+	actualPlatforms := options.Platforms()
+
+	// Then
+	assert.Equal(t, []string{"ios", "tvos", "macos"}, actualPlatforms)
+}
+
+func Test_GivenPlatformAliases_WhenPlatformsCalled_ThenExpectPlatformNames(t *testing.T) {
+	// Given
+	options := Options{Platform: "iOS,Mac osx,macOS"}
+
+	// When
+	actualPlatforms := options.Platforms()
+
+	// Then
+	assert.Equal(t, []string{"ios", "macos"}, actualPlatforms)
+}
+
+func Test_WhenMergeOptionsCalled_ThenExpectMergedOptionsAndArgs(t *testing.T) {
+	testScenarios := []struct {
+		name            string
+		command         string
+		inputOptions    Options
+		customOptions   []string
+		expectedOptions Options
+		expectedArgs    []string
+		expectedErr     string
+	}{
+		{
+			name:            "input options only",
+			inputOptions:    Options{Platform: "ios", ProjectDirectory: "App", CacheBuilds: true, NoUseBinaries: true},
+			expectedOptions: Options{Platform: "ios", ProjectDirectory: "App", CacheBuilds: true, NoUseBinaries: true},
+			expectedArgs:    []string{"--platform", "ios", "--project-directory", "App", "--no-use-binaries", "--cache-builds"},
+		},
+		{
+			name:            "custom options only",
+			customOptions:   []string{"--configuration", "Debug", "--use-xcframeworks", "Alamofire"},
+			expectedOptions: Options{Configuration: "Debug", UseXCFrameworks: true},
+			expectedArgs:    []string{"--configuration", "Debug", "--use-xcframeworks", "Alamofire"},
+		},
+		{
+			name:            "same option given by both",
+			inputOptions:    Options{Platform: "ios", UseSSH: true},
+			customOptions:   []string{"--platform", "ios", "--use-ssh", "--new-resolver"},
+			expectedOptions: Options{Platform: "ios", UseSSH: true, NewResolver: true},
+			expectedArgs:    []string{"--platform", "ios", "--use-ssh", "--new-resolver"},
+		},
+		{
+			name:            "input options not accepted by the command",
+			command:         "outdated",
+			inputOptions:    Options{Platform: "iOS", ProjectDirectory: "App", CacheBuilds: true},
+			customOptions:   []string{"--xcode-warnings"},
+			expectedOptions: Options{Platform: "iOS", ProjectDirectory: "App", CacheBuilds: true},
+			expectedArgs:    []string{"--project-directory", "App", "--xcode-warnings"},
+		},
+		{
+			name:            "same platforms given differently",
+			inputOptions:    Options{Platform: "iOS,tvOS"},
+			customOptions:   []string{"--platform", "tvos ios"},
+			expectedOptions: Options{Platform: "tvos ios"},
+			expectedArgs:    []string{"--platform", "tvos ios"},
+		},
+		{
+			name:          "conflicting platforms",
+			inputOptions:  Options{Platform: "iOS"},
+			customOptions: []string{"--platform", "ios,macOS"},
+			expectedErr:   "conflicting --platform values: iOS (input) and ios,macOS (custom options)",
+		},
+		{
+			name:          "conflicting values",
+			inputOptions:  Options{Configuration: "Release"},
+			customOptions: []string{"--configuration", "Debug"},
+			expectedErr:   "conflicting --configuration values: Release (input) and Debug (custom options)",
+		},
+		{
+			name:          "conflicting negated switches",
+			inputOptions:  Options{NoUseBinaries: true},
+			customOptions: []string{"--use-binaries"},
+			expectedErr:   "conflicting options: --no-use-binaries (input) and --use-binaries (custom options)",
+		},
+	}
+
+	for _, testScenario := range testScenarios {
+		// Given
+		commandName := testScenario.command
+		if commandName == "" {
+			commandName = "bootstrap"
+		}
+		command, err := ParseCommand(commandName)
+		require.NoError(t, err)
+
+		// When
+		actualOptions, actualArgs, err := command.MergeOptions(testScenario.inputOptions, testScenario.customOptions)
+
+		// Then
+		if testScenario.expectedErr != "" {
+			assert.EqualError(t, err, testScenario.expectedErr, testScenario.name)
+			continue
+		}
+		assert.NoError(t, err, testScenario.name)
+		assert.Equal(t, testScenario.expectedOptions, actualOptions, testScenario.name)
+		assert.Equal(t, testScenario.expectedArgs, actualArgs, testScenario.name)
+	}
+}
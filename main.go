@@ -1,10 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	cacheutil "github.com/bitrise-io/go-steputils/cache"
 	"github.com/bitrise-io/go-steputils/input"
@@ -12,6 +20,8 @@ import (
 	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/filedownloader"
 	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-carthage/archivecache"
 	"github.com/bitrise-steplib/steps-carthage/cachedcarthage"
 	"github.com/bitrise-steplib/steps-carthage/carthage"
 	"github.com/hashicorp/go-version"
@@ -19,9 +29,40 @@ import (
 )
 
 const (
-	projectDirArg = "--project-directory"
+	buildInventoryFileName   = "carthage-build-inventory.json"
+	buildInventoryPathEnvKey = "CARTHAGE_BUILD_INVENTORY_PATH"
+
+	dependencyGraphDOTFileName    = "carthage-dependency-graph.dot"
+	dependencyGraphJSONFileName   = "carthage-dependency-graph.json"
+	dependencyGraphDOTPathEnvKey  = "CARTHAGE_DEPENDENCY_GRAPH_DOT_PATH"
+	dependencyGraphJSONPathEnvKey = "CARTHAGE_DEPENDENCY_GRAPH_JSON_PATH"
+
+	// The roots of the paths in the cache archives.
+	projectCacheRoot = "project"
+	homeCacheRoot    = "home"
+
+	// carthageKitCacheDir is the global cache dir of CarthageKit, relative to the home dir.
+	carthageKitCacheDir = "Library/Caches/org.carthage.CarthageKit"
+)
+
+const (
+	cacheStepsBackend = "cache-steps"
+	localCacheBackend = "local"
+	s3CacheBackend    = "s3"
+
+	cacheKeyPrefix = "carthage-"
 )
 
+const keepGoingBuildFailureMode = "keep-going"
+
+var platformSDKs = map[string]string{
+	"ios":      "iphoneos",
+	"macos":    "macosx",
+	"tvos":     "appletvos",
+	"watchos":  "watchos",
+	"visionos": "xros",
+}
+
 // FileProvider ...
 type FileProvider interface {
 	LocalPath(path string) (string, error)
@@ -29,15 +70,60 @@ type FileProvider interface {
 
 // Config ...
 type Config struct {
-	GithubAccessToken stepconf.Secret `env:"github_access_token"`
-	CarthageCommand   string          `env:"carthage_command,required"`
-	CarthageOptions   string          `env:"carthage_options"`
-	SourceDir         string          `env:"BITRISE_SOURCE_DIR"`
-	Xcconfig          string          `env:"xcconfig"`
-	XcconfigFromEnv   string          `env:"XCODE_XCCONFIG_FILE"`
+	GithubAccessToken     stepconf.Secret `env:"github_access_token"`
+	CarthageCommand       string          `env:"carthage_command,required"`
+	CarthageOptions       string          `env:"carthage_options"`
+	SourceDir             string          `env:"BITRISE_SOURCE_DIR"`
+	DeployDir             string          `env:"BITRISE_DEPLOY_DIR"`
+	Xcconfig              string          `env:"xcconfig"`
+	XcconfigFromEnv       string          `env:"XCODE_XCCONFIG_FILE"`
+	RequirementCheck      string          `env:"requirement_check,opt[fail,warn,no]"`
+	SplitCheckoutAndBuild bool            `env:"split_checkout_and_build,opt[yes,no]"`
+	CacheCarthageKit      bool            `env:"cache_carthagekit,opt[yes,no]"`
+	Offline               bool            `env:"offline,opt[yes,no]"`
+
+	// Parallel builds
+	BuildWorkers     int    `env:"build_workers,range[1..16]"`
+	BuildFailureMode string `env:"build_failure_mode,opt[fail-fast,keep-going]"`
+
+	// Update drift
+	ResolvedDriftCheck               string `env:"resolved_drift_check,opt[no,report,fail]"`
+	ResolvedDriftAllowedDependencies string `env:"resolved_drift_allowed_dependencies"`
+
+	// Carthage options
+	Platform         string `env:"platform"`
+	Configuration    string `env:"configuration"`
+	UseXCFrameworks  bool   `env:"use_xcframeworks,opt[yes,no]"`
+	UseSSH           bool   `env:"use_ssh,opt[yes,no]"`
+	UseSubmodules    bool   `env:"use_submodules,opt[yes,no]"`
+	NoUseBinaries    bool   `env:"no_use_binaries,opt[yes,no]"`
+	CacheBuilds      bool   `env:"cache_builds,opt[yes,no]"`
+	NewResolver      bool   `env:"new_resolver,opt[yes,no]"`
+	DerivedData      string `env:"derived_data"`
+	Toolchain        string `env:"toolchain"`
+	ProjectDirectory string `env:"project_directory"`
+
+	// Multiple projects
+	ProjectDirectories string `env:"project_directories"`
+	ProjectParallelism int    `env:"project_parallelism,range[1..8]"`
+
+	// Cache
+	CacheBackend           string          `env:"cache_backend,opt[cache-steps,local,s3]"`
+	CacheLocalDir          string          `env:"cache_local_dir"`
+	CacheS3BucketURL       string          `env:"cache_s3_bucket_url"`
+	CacheS3Region          string          `env:"cache_s3_region"`
+	CacheS3AccessKeyID     stepconf.Secret `env:"cache_s3_access_key_id"`
+	CacheS3SecretAccessKey stepconf.Secret `env:"cache_s3_secret_access_key"`
+	ArtifactStoreDir       string          `env:"artifact_store_dir"`
+
+	// Retry
+	RetryCount         int     `env:"retry_count,range[0..10]"`
+	RetryWaitSeconds   float64 `env:"retry_wait_seconds,range[0..600]"`
+	RetryBackoffFactor float64 `env:"retry_backoff_factor,range[1..10]"`
 
 	// Debug
 	VerboseLog bool `env:"verbose_log,opt[yes,no]"`
+	DryRun     bool `env:"dry_run,opt[yes,no]"`
 }
 
 func fail(format string, v ...interface{}) {
@@ -54,6 +140,11 @@ func main() {
 
 	log.SetEnableDebugLog(configs.VerboseLog)
 
+	carthageCommand, err := carthage.ParseCommand(configs.CarthageCommand)
+	if err != nil {
+		fail("Invalid Carthage command, error: %s", err)
+	}
+
 	// Environment
 	fmt.Println()
 	log.Infof("Environment:")
@@ -69,32 +160,378 @@ func main() {
 		fail("Failed to get swift version, error: %s", err)
 	}
 	log.Printf("- SwiftVersion: %s", strings.Replace(swiftVersion, "\n", "- ", -1))
+
+	// The Xcode and SDK versions are components of the cache fingerprint, needed only by the commands building the dependencies.
+	var xcodeVersion string
+	if buildsDependencies(carthageCommand) {
+		if xcodeVersion, err = getXcodeVersion(); err != nil {
+			log.Warnf("Failed to get Xcode version, the cache fingerprint does not include it, error: %s", err)
+			xcodeVersion = ""
+		} else {
+			log.Printf("- XcodeVersion: %s", strings.Replace(xcodeVersion, "\n", " - ", -1))
+		}
+	}
 	// --
 
 	// Parse options
-	args := parseCarthageOptions(configs)
+	customOptions, warnings := carthage.NormalizeArgs(parseCarthageOptions(configs))
+	for _, warning := range warnings {
+		log.Warnf("%s", warning)
+	}
+	options, args, err := carthageCommand.MergeOptions(inputOptions(configs), customOptions)
+	if err != nil {
+		fail("Invalid Carthage options, error: %s", err)
+	}
+	options, args, err = carthage.ResolveProjectDirectory(options, args, configs.SourceDir)
+	if err != nil {
+		fail("Invalid project directory, error: %s", err)
+	}
+	if err := carthageCommand.ValidateOptions(args); err != nil {
+		fail("Invalid Carthage options, error: %s", err)
+	}
+
 	fileProvider := input.NewFileProvider(filedownloader.New(http.DefaultClient))
 	xconfigPath, err := parseXCConfigPath(configs.Xcconfig, configs.XcconfigFromEnv, fileProvider)
 	if err != nil {
 		fail("Failed to get xcconfig file, error: %s", err)
 	}
 
-	projectDir := parseProjectDir(configs.SourceDir, args)
+	var sdkVersions string
+	if buildsDependencies(carthageCommand) {
+		sdkVersions = getSDKVersions(options.Platforms())
+	}
+
+	fingerprint, err := createFingerprint(swiftVersion, xcodeVersion, sdkVersions, xconfigPath, options)
+	if err != nil {
+		fail("Failed to create cache fingerprint, error: %s", err)
+	}
+
+	projectDirs, err := parseProjectDirs(configs.SourceDir, configs.ProjectDirectories)
+	if err != nil {
+		fail("Invalid project directories, error: %s", err)
+	}
+	if len(projectDirs) > 0 {
+		if options.ProjectDirectory != "" {
+			fail("Both project_directories and the project directory (%s) are set, use only one of them", options.ProjectDirectory)
+		}
+		runProjects(configs, carthageCommand, projectDirs, options, args, xconfigPath, fingerprint)
+		return
+	}
+
+	projectDir := parseProjectDir(configs.SourceDir, options)
 	project := cachedcarthage.NewProject(projectDir)
-	filecache := cacheutil.New()
+	filecache, err := createFileCache(configs, cacheKeyPrefix+fingerprint.Hash(), projectDir)
+	if err != nil {
+		fail("Failed to create cache, error: %s", err)
+	}
+	outputExporter := cachedcarthage.NewEnvmanOutputExporter()
+
+	carthageKitDir := ""
+	if configs.CacheCarthageKit {
+		carthageKitDir = filepath.Join(pathutil.UserHomeDir(), carthageKitCacheDir)
+	}
+
+	runner := createRunner(configs, project, args, xconfigPath, fingerprint, filecache, carthageKitDir, outputExporter)
+	if configs.DryRun {
+		printFingerprint(fingerprint)
+		if err := runner.Plan(); err != nil {
+			fail("Failed to plan step: %s", err)
+		}
+		return
+	}
+
+	if err := runner.Run(); err != nil {
+		logRemediation(err)
+		fail("Failed to execute step: %s", err)
+	}
+
+	if configs.DeployDir != "" {
+		if err := exportBuildInventory(project, configs.DeployDir, outputExporter); err != nil {
+			log.Warnf("Failed to export build inventory: %s", err)
+		}
+		if err := exportDependencyGraph(project, configs.DeployDir, outputExporter); err != nil {
+			log.Warnf("Failed to export dependency graph: %s", err)
+		}
+	}
+}
+
+// runProjects runs Carthage in every project dir, each with its own cache.
+func runProjects(configs Config, carthageCommand carthage.Command, projectDirs []string, options carthage.Options, args []string, xcconfigPath string, fingerprint cachedcarthage.Fingerprint) {
+	var commitLock sync.Mutex
+	var runners []cachedcarthage.Runner
+	for i, projectDir := range projectDirs {
+		projectOptions := carthage.Options{ProjectDirectory: projectDir}
+		if configs.ProjectParallelism > 1 && options.DerivedData == "" && buildsDependencies(carthageCommand) && !configs.DryRun {
+			// Carthage builds every dependency version in the same derived data dir by default,
+			// which breaks the builds of the same dependency in projects run in parallel.
+			derivedDataDir, err := ioutil.TempDir("", "carthage-derived-data")
+			if err != nil {
+				fail("Failed to create derived data dir, error: %s", err)
+			}
+			projectOptions.DerivedData = derivedDataDir
+		}
+		_, projectArgs, err := carthageCommand.MergeOptions(projectOptions, args)
+		if err != nil {
+			fail("Invalid Carthage options, error: %s", err)
+		}
+		if err := carthageCommand.ValidateOptions(projectArgs); err != nil {
+			fail("Invalid Carthage options, error: %s", err)
+		}
+
+		filecache, err := createFileCache(configs, projectCacheKey(configs.SourceDir, projectDir, fingerprint), projectDir)
+		if err != nil {
+			fail("Failed to create cache, error: %s", err)
+		}
+
+		// The CarthageKit caches are global, they are cached with the first project.
+		carthageKitDir := ""
+		if configs.CacheCarthageKit && i == 0 {
+			carthageKitDir = filepath.Join(pathutil.UserHomeDir(), carthageKitCacheDir)
+		}
+
+		project := cachedcarthage.NewProject(projectDir)
+		synchronizedFileCache := cachedcarthage.NewSynchronizedFileCache(filecache, &commitLock)
+		runners = append(runners, createRunner(configs, project, projectArgs, xcconfigPath, fingerprint, synchronizedFileCache, carthageKitDir, nil))
+	}
+
+	if configs.DryRun {
+		printFingerprint(fingerprint)
+		for _, runner := range runners {
+			if err := runner.Plan(); err != nil {
+				fail("Failed to plan step: %s", err)
+			}
+		}
+		return
+	}
+
+	results, err := cachedcarthage.RunProjects(runners, configs.ProjectParallelism, configs.DeployDir, cachedcarthage.NewEnvmanOutputExporter())
+	if err != nil {
+		for _, result := range results {
+			if result.Err != nil {
+				logRemediation(result.Err)
+			}
+		}
+		fail("Failed to execute step: %s", err)
+	}
+}
+
+func buildsDependencies(carthageCommand carthage.Command) bool {
+	switch carthageCommand.Name {
+	case carthage.BootstrapCommand, carthage.UpdateCommand, carthage.BuildCommand:
+		return true
+	default:
+		return false
+	}
+}
+
+func createRunner(
+	configs Config,
+	project cachedcarthage.Project,
+	args []string,
+	xcconfigPath string,
+	fingerprint cachedcarthage.Fingerprint,
+	filecache cachedcarthage.FileCache,
+	carthageKitDir string,
+	outputExporter cachedcarthage.OutputExporter,
+) cachedcarthage.Runner {
 	stateProvider := cachedcarthage.DefaultStateProvider{}
 
-	runner := cachedcarthage.NewRunner(
+	var artifactCache cachedcarthage.DependencyArtifactCache
+	if configs.ArtifactStoreDir != "" {
+		artifactStore := archivecache.NewArtifactStore(archivecache.NewLocalStorage(configs.ArtifactStoreDir))
+		artifactCache = cachedcarthage.NewArtifactCache(project, fingerprint, artifactStore, stateProvider)
+	}
+
+	buildCommand, err := carthage.ParseCommand(carthage.BuildCommand)
+	if err != nil {
+		fail("Failed to create build command, error: %s", err)
+	}
+	parallelBuild := cachedcarthage.NewParallelBuild(uint(configs.BuildWorkers), configs.BuildFailureMode == keepGoingBuildFailureMode, buildCommand.FilterOptions(args))
+
+	return cachedcarthage.NewRunner(
 		configs.CarthageCommand,
 		args,
 		configs.GithubAccessToken,
-		xconfigPath,
-		cachedcarthage.NewCache(project, swiftVersion, &filecache, stateProvider),
+		xcconfigPath,
+		cachedcarthage.NewCache(project, fingerprint, carthageKitDir, filecache, stateProvider),
 		carthage.NewCLIBuilder(),
+		cachedcarthage.NewProjectRequirementChecker(project),
+		cachedcarthage.RequirementCheckMode(configs.RequirementCheck),
+		project,
+		configs.DeployDir,
+		outputExporter,
+		cachedcarthage.RunnerOptions{
+			ArtifactCache:         artifactCache,
+			RetryPolicy:           cachedcarthage.NewRetryPolicy(uint(configs.RetryCount), time.Duration(configs.RetryWaitSeconds*float64(time.Second)), configs.RetryBackoffFactor),
+			SplitCheckoutAndBuild: configs.SplitCheckoutAndBuild,
+			ParallelBuild:         parallelBuild,
+			DriftGuard:            cachedcarthage.NewDriftGuard(cachedcarthage.ResolvedDriftMode(configs.ResolvedDriftCheck), parseAllowedDependencies(configs.ResolvedDriftAllowedDependencies, args), stateProvider),
+			Offline:               configs.Offline,
+		},
 	)
-	if err := runner.Run(); err != nil {
-		fail("Failed to execute step: %s", err)
+}
+
+// parseAllowedDependencies returns the dependencies listed in the input (separated by commas or new lines),
+// or the dependencies given to the Carthage command if the input is empty.
+func parseAllowedDependencies(value string, args []string) []string {
+	var dependencies []string
+	for _, line := range strings.Split(value, "\n") {
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				dependencies = append(dependencies, name)
+			}
+		}
+	}
+	if len(dependencies) == 0 {
+		return carthage.PositionalArgs(args)
+	}
+	return dependencies
+}
+
+// projectCacheKey returns the cache key of a project of a multi-project run, which contains the hash of the project dir
+// relative to the source dir, so the projects do not share their cache archives.
+func projectCacheKey(sourceDir, projectDir string, fingerprint cachedcarthage.Fingerprint) string {
+	rel, err := filepath.Rel(sourceDir, projectDir)
+	if err != nil {
+		rel = projectDir
+	}
+	relHash := sha256.Sum256([]byte(filepath.ToSlash(rel)))
+	return fmt.Sprintf("%s%x-%s", cacheKeyPrefix, relHash[:8], fingerprint.Hash())
+}
+
+// createFileCache creates the cache of the selected backend. The local and s3 backends are restored here, unless in dry run,
+// the cache-steps backend is restored by the Cache:Pull step.
+// createFileCache creates the cache of the backend. The archives of the local and s3 backends keep the paths relative to
+// the project dir, or to the home dir for the CarthageKit caches, so they are restored only under these dirs.
+func createFileCache(configs Config, cacheKey, projectDir string) (cachedcarthage.FileCache, error) {
+	var storage archivecache.Storage
+	switch configs.CacheBackend {
+	case localCacheBackend:
+		if configs.CacheLocalDir == "" {
+			return nil, errors.New("cache_local_dir input is required for the local cache backend")
+		}
+		storage = archivecache.NewLocalStorage(configs.CacheLocalDir)
+	case s3CacheBackend:
+		if configs.CacheS3BucketURL == "" {
+			return nil, errors.New("cache_s3_bucket_url input is required for the s3 cache backend")
+		}
+		s3Storage, err := archivecache.NewS3Storage(configs.CacheS3BucketURL, configs.CacheS3Region, string(configs.CacheS3AccessKeyID), string(configs.CacheS3SecretAccessKey))
+		if err != nil {
+			return nil, err
+		}
+		storage = s3Storage
+	default:
+		filecache := cacheutil.New()
+		return &filecache, nil
+	}
+
+	absProjectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	cache := archivecache.New(storage, cacheKey, map[string]string{
+		projectCacheRoot: absProjectDir,
+		homeCacheRoot:    pathutil.UserHomeDir(),
+	})
+	if configs.DryRun {
+		return cache, nil
+	}
+
+	fmt.Println()
+	log.Infof("Restoring cache from the %s backend", configs.CacheBackend)
+	if restored, err := cache.Restore(); err != nil {
+		log.Warnf("Failed to restore cache: %s", err)
+	} else if restored {
+		log.Donef("Cache restored")
+	} else {
+		log.Printf("No cache found for the current fingerprint")
+	}
+
+	return cache, nil
+}
+
+func printFingerprint(fingerprint cachedcarthage.Fingerprint) {
+	content, err := json.MarshalIndent(fingerprint, "", "  ")
+	if err != nil {
+		log.Warnf("Failed to print cache fingerprint: %s", err)
+		return
+	}
+
+	fmt.Println()
+	log.Infof("Cache fingerprint:")
+	log.Printf("%s", content)
+}
+
+// logRemediation prints how to fix the Carthage command failure, if its reason is known.
+func logRemediation(err error) {
+	var runnerErr *cachedcarthage.RunnerError
+	if !errors.As(err, &runnerErr) || runnerErr.Reason.Remediation() == "" {
+		return
+	}
+
+	fmt.Println()
+	log.Warnf("Failure reason: %s", runnerErr.Reason)
+	log.Printf("%s", runnerErr.Reason.Remediation())
+}
+
+func exportBuildInventory(project cachedcarthage.Project, deployDir string, outputExporter cachedcarthage.OutputExporter) error {
+	inventory, err := cachedcarthage.CollectBuildInventory(project)
+	if err != nil {
+		return fmt.Errorf("failed to collect build products, error: %s", err)
+	}
+
+	content, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	pth := filepath.Join(deployDir, buildInventoryFileName)
+	if err := ioutil.WriteFile(pth, content, 0666); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", pth, err)
+	}
+
+	if err := outputExporter.ExportOutput(buildInventoryPathEnvKey, pth); err != nil {
+		return fmt.Errorf("failed to export %s, error: %s", buildInventoryPathEnvKey, err)
 	}
+
+	fmt.Println()
+	log.Donef("Build inventory of %d build products exported: %s", len(inventory.Products), pth)
+	return nil
+}
+
+func exportDependencyGraph(project cachedcarthage.Project, deployDir string, outputExporter cachedcarthage.OutputExporter) error {
+	graph, err := cachedcarthage.CollectResolvedGraph(project)
+	if err != nil {
+		return fmt.Errorf("failed to collect dependency graph, error: %s", err)
+	}
+
+	content, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dotPth := filepath.Join(deployDir, dependencyGraphDOTFileName)
+	if err := ioutil.WriteFile(dotPth, []byte(graph.DOT()), 0666); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", dotPth, err)
+	}
+	jsonPth := filepath.Join(deployDir, dependencyGraphJSONFileName)
+	if err := ioutil.WriteFile(jsonPth, content, 0666); err != nil {
+		return fmt.Errorf("failed to write %s, error: %s", jsonPth, err)
+	}
+
+	if err := outputExporter.ExportOutput(dependencyGraphDOTPathEnvKey, dotPth); err != nil {
+		return fmt.Errorf("failed to export %s, error: %s", dependencyGraphDOTPathEnvKey, err)
+	}
+	if err := outputExporter.ExportOutput(dependencyGraphJSONPathEnvKey, jsonPth); err != nil {
+		return fmt.Errorf("failed to export %s, error: %s", dependencyGraphJSONPathEnvKey, err)
+	}
+
+	fmt.Println()
+	log.Donef("Dependency graph of %d dependencies exported: %s, %s", len(graph.Nodes)-1, dotPth, jsonPth)
+	for _, conflict := range graph.Conflicts {
+		log.Warnf("%s", conflict)
+	}
+	return nil
 }
 
 func parseXCConfigPath(pathFromStepInput string, pathFromEnv string, fileProvider FileProvider) (string, error) {
@@ -118,6 +555,23 @@ func parseXCConfigPath(pathFromStepInput string, pathFromEnv string, fileProvide
 	return pathToUse, nil
 }
 
+// inputOptions returns the Carthage options given by the dedicated step inputs.
+func inputOptions(config Config) carthage.Options {
+	return carthage.Options{
+		Platform:         config.Platform,
+		Configuration:    config.Configuration,
+		DerivedData:      config.DerivedData,
+		Toolchain:        config.Toolchain,
+		ProjectDirectory: config.ProjectDirectory,
+		UseXCFrameworks:  config.UseXCFrameworks,
+		UseSSH:           config.UseSSH,
+		UseSubmodules:    config.UseSubmodules,
+		NoUseBinaries:    config.NoUseBinaries,
+		CacheBuilds:      config.CacheBuilds,
+		NewResolver:      config.NewResolver,
+	}
+}
+
 func parseCarthageOptions(config Config) []string {
 	var customCarthageOptions []string
 	if config.CarthageOptions != "" {
@@ -153,26 +607,123 @@ func getSwiftVersion() (string, error) {
 	return cmd.RunAndReturnTrimmedCombinedOutput()
 }
 
-func parseProjectDir(originalDir string, customCarthageOptions []string) string {
-	projectDir := originalDir
+func getXcodeVersion() (string, error) {
+	cmd := command.New("xcodebuild", "-version")
+	return cmd.RunAndReturnTrimmedCombinedOutput()
+}
+
+func getSDKVersions(platforms []string) string {
+	sdks := map[string]bool{}
+	for _, platform := range platforms {
+		if sdk, ok := platformSDKs[platform]; ok {
+			sdks[sdk] = true
+		}
+	}
+	if len(sdks) == 0 {
+		for _, sdk := range platformSDKs {
+			sdks[sdk] = true
+		}
+	}
 
-	isNextOptionProjectDir := false
-	for _, option := range customCarthageOptions {
-		if option == projectDirArg {
-			isNextOptionProjectDir = true
+	var sdkVersions []string
+	for sdk := range sdks {
+		cmd := command.New("xcrun", "--sdk", sdk, "--show-sdk-version")
+		out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+		if err != nil {
+			log.Debugf("Failed to get %s SDK version: %s", sdk, out)
 			continue
 		}
+		sdkVersions = append(sdkVersions, fmt.Sprintf("%s %s", sdk, out))
+	}
+	sort.Strings(sdkVersions)
 
-		if isNextOptionProjectDir {
-			projectDir = option
+	return strings.Join(sdkVersions, ", ")
+}
 
-			fmt.Println()
-			log.Infof("--project-directory flag found with value: %s", projectDir)
-			log.Printf("using %s as working directory", projectDir)
+func createFingerprint(swiftVersion, xcodeVersion, sdkVersions, xcconfigPath string, options carthage.Options) (cachedcarthage.Fingerprint, error) {
+	platforms := options.Platforms()
 
-			break
+	xcconfigHash := ""
+	if xcconfigPath != "" {
+		content, err := ioutil.ReadFile(xcconfigPath)
+		if err != nil {
+			return cachedcarthage.Fingerprint{}, fmt.Errorf("failed to read xcconfig file (%s), error: %s", xcconfigPath, err)
 		}
+		xcconfigHash = fmt.Sprintf("%x", sha256.Sum256(content))
 	}
 
-	return projectDir
+	return cachedcarthage.Fingerprint{
+		SwiftVersion:    swiftVersion,
+		XcodeVersion:    xcodeVersion,
+		SDKVersions:     sdkVersions,
+		Platforms:       platforms,
+		Configuration:   options.Configuration,
+		UseXCFrameworks: options.UseXCFrameworks,
+		NoUseBinaries:   options.NoUseBinaries,
+		XcconfigHash:    xcconfigHash,
+	}, nil
+}
+
+// parseProjectDirs returns the absolute paths of the project dirs listed in the input, one dir or glob pattern per line,
+// relative to the source dir. Listed dirs need to contain a Cartfile, the dirs matching a pattern without a Cartfile are skipped.
+func parseProjectDirs(sourceDir, value string) ([]string, error) {
+	var projectDirs []string
+	seen := map[string]bool{}
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			projectDirs = append(projectDirs, dir)
+		}
+	}
+
+	for _, line := range strings.Split(value, "\n") {
+		pattern := strings.TrimSpace(line)
+		if pattern == "" {
+			continue
+		}
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			options, _, err := carthage.ResolveProjectDirectory(carthage.Options{ProjectDirectory: pattern}, nil, sourceDir)
+			if err != nil {
+				return nil, err
+			}
+			add(options.ProjectDirectory)
+			continue
+		}
+
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(sourceDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern (%s), error: %s", line, err)
+		}
+		found := false
+		for _, match := range matches {
+			options, _, err := carthage.ResolveProjectDirectory(carthage.Options{ProjectDirectory: match}, nil, sourceDir)
+			if err != nil {
+				log.Debugf("Skipping %s: %s", match, err)
+				continue
+			}
+			found = true
+			add(options.ProjectDirectory)
+		}
+		if !found {
+			return nil, fmt.Errorf("no project directory with a Cartfile matches %s", strings.TrimSpace(line))
+		}
+	}
+
+	return projectDirs, nil
+}
+
+func parseProjectDir(originalDir string, options carthage.Options) string {
+	if options.ProjectDirectory == "" {
+		return originalDir
+	}
+
+	fmt.Println()
+	log.Infof("Project directory set: %s", options.ProjectDirectory)
+	log.Printf("using %s as working directory", options.ProjectDirectory)
+
+	return options.ProjectDirectory
 }
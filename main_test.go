@@ -2,19 +2,25 @@ package main
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/bitrise-steplib/steps-carthage/cachedcarthage"
+	"github.com/bitrise-steplib/steps-carthage/carthage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // parseProjectDir
 func Test_GivenCustomDirProvided_WhenParseProjectDirCalled_ThenExpectCustomDir(t *testing.T) {
 	// Given
 	expectedDir := "/customDir"
-	customOptions := []string{"--project-directory", expectedDir}
+	options := carthage.Options{ProjectDirectory: expectedDir}
 
 	// When
-	acutalProjectDir := parseProjectDir("/originalDir", customOptions)
+	acutalProjectDir := parseProjectDir("/originalDir", options)
 
 	//Then
 	assert.Equal(t, expectedDir, acutalProjectDir)
@@ -23,15 +29,91 @@ func Test_GivenCustomDirProvided_WhenParseProjectDirCalled_ThenExpectCustomDir(t
 func Test_GivenCustomDirNotProvided_WhenParseProjectDirCalled_ThenExpectOriginalDir(t *testing.T) {
 	// Given
 	expectedDir := "/originalDir"
-	customOptions := []string{"--someparam", `"some value"`}
+	options := carthage.Options{Configuration: "Debug"}
 
 	// When
-	acutalProjectDir := parseProjectDir(expectedDir, customOptions)
+	acutalProjectDir := parseProjectDir(expectedDir, options)
 
 	//Then
 	assert.Equal(t, expectedDir, acutalProjectDir)
 }
 
+// parseProjectDirs
+func Test_GivenDirsAndPatterns_WhenParseProjectDirsCalled_ThenExpectProjectDirsWithCartfile(t *testing.T) {
+	// Given
+	sourceDir := t.TempDir()
+	for _, dir := range []string{"Apps/App1", "Apps/App2", "Apps/Docs", "Shared"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, dir), 0777))
+		if dir != "Apps/Docs" {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, dir, "Cartfile"), nil, 0666))
+		}
+	}
+
+	// When
+	projectDirs, err := parseProjectDirs(sourceDir, "Shared\n\nApps/*\nApps/App1\n")
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(sourceDir, "Shared"),
+		filepath.Join(sourceDir, "Apps/App1"),
+		filepath.Join(sourceDir, "Apps/App2"),
+	}, projectDirs)
+}
+
+func Test_GivenInvalidDirs_WhenParseProjectDirsCalled_ThenExpectError(t *testing.T) {
+	// Given
+	sourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "Apps", "Docs"), 0777))
+
+	// When
+	_, missingDirErr := parseProjectDirs(sourceDir, "Apps/Docs")
+	_, noMatchErr := parseProjectDirs(sourceDir, "Apps/*")
+
+	// Then
+	assert.EqualError(t, missingDirErr, "no Cartfile found in the project directory: "+filepath.Join(sourceDir, "Apps", "Docs"))
+	assert.EqualError(t, noMatchErr, "no project directory with a Cartfile matches Apps/*")
+}
+
+// projectCacheKey
+func Test_GivenProjectDirs_WhenProjectCacheKeyCalled_ThenExpectKeyPerProject(t *testing.T) {
+	// Given
+	fingerprint := cachedcarthage.Fingerprint{SwiftVersion: "5.5"}
+
+	// When
+	app1Key := projectCacheKey("/source", "/source/Apps/App1", fingerprint)
+	app2Key := projectCacheKey("/source", "/source/Apps/App2", fingerprint)
+
+	// Then
+	assert.NotEqual(t, app1Key, app2Key)
+	assert.Equal(t, app1Key, projectCacheKey("/other", "/other/Apps/App1", fingerprint))
+	assert.Regexp(t, "^carthage-[0-9a-f]{16}-"+fingerprint.Hash()+"$", app1Key)
+}
+
+func Test_WhenParseAllowedDependenciesCalled_ThenExpectDependencies(t *testing.T) {
+	testScenarios := []struct {
+		name     string
+		value    string
+		args     []string
+		expected []string
+	}{
+		{name: "comma separated", value: "Alamofire, Moya", args: []string{"RxSwift"}, expected: []string{"Alamofire", "Moya"}},
+		{name: "one per line", value: "Alamofire\nMoya\n", expected: []string{"Alamofire", "Moya"}},
+		{name: "dependencies of the command", args: []string{"--platform", "iOS", "Alamofire"}, expected: []string{"Alamofire"}},
+		{name: "none", args: []string{"--platform", "iOS"}},
+	}
+
+	for _, testScenario := range testScenarios {
+		t.Run(testScenario.name, func(t *testing.T) {
+			// When
+			dependencies := parseAllowedDependencies(testScenario.value, testScenario.args)
+
+			// Then
+			assert.Equal(t, testScenario.expected, dependencies)
+		})
+	}
+}
+
 // parseCarthageOptions
 func Test_WhenParseCarthageOptionsCalled_ThenExpectCorrectValue(t *testing.T) {
 	// Given
@@ -47,6 +129,53 @@ func Test_WhenParseCarthageOptionsCalled_ThenExpectCorrectValue(t *testing.T) {
 	assert.Equal(t, expectedOpts, actualOpts)
 }
 
+// createFingerprint
+func Test_GivenCustomOptionsAndXCConfig_WhenCreateFingerprintCalled_ThenExpectFingerprint(t *testing.T) {
+	// Given
+	xcconfigPath := filepath.Join(t.TempDir(), "static.xcconfig")
+	require.NoError(t, ioutil.WriteFile(xcconfigPath, []byte("BUILD_LIBRARY_FOR_DISTRIBUTION = YES"), 0666))
+	options := carthage.Options{Platform: "iOS", Configuration: "Debug", UseXCFrameworks: true}
+
+	// When
+	fingerprint, err := createFingerprint("5.5", "13C100", "iphoneos 15.2", xcconfigPath, options)
+
+	// Then
+	require.NoError(t, err)
+	assert.Equal(t, "5.5", fingerprint.SwiftVersion)
+	assert.Equal(t, "13C100", fingerprint.XcodeVersion)
+	assert.Equal(t, "iphoneos 15.2", fingerprint.SDKVersions)
+	assert.Equal(t, []string{"ios"}, fingerprint.Platforms)
+	assert.Equal(t, "Debug", fingerprint.Configuration)
+	assert.True(t, fingerprint.UseXCFrameworks)
+	assert.False(t, fingerprint.NoUseBinaries)
+	assert.Equal(t, "142c0a1764bfe127d7b10f77676cddcc42d62f19a2efffe785d53bfcb64157b1", fingerprint.XcconfigHash)
+}
+
+func Test_GivenPlatformAliases_WhenCreateFingerprintCalled_ThenExpectSameHash(t *testing.T) {
+	// Given
+	aliases := []string{"macOS", "mac", "osx", "Mac,macos"}
+
+	for _, alias := range aliases {
+		// When
+		fingerprint, err := createFingerprint("5.5", "13C100", "iphoneos 15.2", "", carthage.Options{Platform: "iOS," + alias})
+		expected, expectedErr := createFingerprint("5.5", "13C100", "iphoneos 15.2", "", carthage.Options{Platform: "iOS,macOS"})
+
+		// Then
+		require.NoError(t, err, alias)
+		require.NoError(t, expectedErr, alias)
+		assert.Equal(t, []string{"ios", "macos"}, fingerprint.Platforms, alias)
+		assert.Equal(t, expected.Hash(), fingerprint.Hash(), alias)
+	}
+}
+
+func Test_GivenMissingXCConfig_WhenCreateFingerprintCalled_ThenExpectError(t *testing.T) {
+	// When
+	_, err := createFingerprint("5.5", "13C100", "iphoneos 15.2", "/not/existing.xcconfig", carthage.Options{})
+
+	// Then
+	assert.Error(t, err)
+}
+
 // parseXCConfigPath
 func Test_GivenXCConfigAsInputAndFileProviderSucceeds_WhenParseXCConfigPathCalled_ThenExpectPath(t *testing.T) {
 	// Given